@@ -0,0 +1,718 @@
+package main
+
+import (
+	"strconv"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	errorsPackage         = protogen.GoImportPath("errors")
+	fmtPackage            = protogen.GoImportPath("fmt")
+	fieldmaskUtilsPackage = protogen.GoImportPath("github.com/mennanov/fieldmask-utils")
+
+	anyMessageName = protoreflect.FullName("google.protobuf.Any")
+)
+
+// generateFile generates the <name>_fieldmask.pb.go file for the given proto file.
+func generateFile(gen *protogen.Plugin, file *protogen.File) {
+	if len(file.Messages) == 0 {
+		return
+	}
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_fieldmask.pb.go", file.GoImportPath)
+	g.P("// Code generated by protoc-gen-go-fieldmask. DO NOT EDIT.")
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	gn := &generator{gen: gen, g: g}
+	for _, message := range file.Messages {
+		gn.message(message)
+	}
+}
+
+// generator generates the methods of the messages of a single file.
+type generator struct {
+	gen *protogen.Plugin
+	g   *protogen.GeneratedFile
+}
+
+// valueKind defines how a singular value (a field, a list item or a map value) is copied.
+type valueKind int
+
+const (
+	// scalarValue is copied by assignment.
+	scalarValue valueKind = iota
+	// optionalValue is a pointer to a scalar value (a field with explicit presence).
+	optionalValue
+	// bytesValue is copied as a list of bytes.
+	bytesValue
+	// messageValue is a message which has the generated methods.
+	messageValue
+	// externalMessageValue is a message which does not have the generated methods: it is copied with reflection.
+	externalMessageValue
+	// anyValue is a google.protobuf.Any message.
+	anyValue
+)
+
+// value describes a singular value.
+type value struct {
+	kind valueKind
+	// field is the field holding the value (or the items of the list, or the map entry values).
+	field *protogen.Field
+}
+
+// usesFilter returns true if copying the value depends on its filter.
+func (v value) usesFilter() bool {
+	return v.kind != scalarValue && v.kind != optionalValue
+}
+
+// isMessage returns true if the value is a pointer to a message.
+func (v value) isMessage() bool {
+	return v.kind == messageValue || v.kind == externalMessageValue || v.kind == anyValue
+}
+
+// goType returns the Go type of the value. It is the type of the struct for messages and the type of the pointee for
+// optional values. The types are only qualified when used, so that the unused packages are not imported.
+func (gn *generator) goType(v value) string {
+	if v.isMessage() {
+		return gn.g.QualifiedGoIdent(v.field.Message.GoIdent)
+	}
+	return gn.scalarType(v.field)
+}
+
+// fieldType returns the Go type of the field holding the value.
+func (gn *generator) fieldType(v value) string {
+	if v.isMessage() || v.kind == optionalValue {
+		return "*" + gn.goType(v)
+	}
+	return gn.goType(v)
+}
+
+// newValue returns the value of the given field or of its items for repeated fields.
+func (gn *generator) newValue(field *protogen.Field) value {
+	switch field.Desc.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if field.Message.Desc.FullName() == anyMessageName {
+			return value{kind: anyValue, field: field}
+		}
+		if f, ok := gn.gen.FilesByPath[field.Message.Location.SourceFile]; ok && f.Generate {
+			return value{kind: messageValue, field: field}
+		}
+		return value{kind: externalMessageValue, field: field}
+
+	case protoreflect.BytesKind:
+		return value{kind: bytesValue, field: field}
+	}
+	if field.Desc.HasPresence() && !field.Desc.IsList() && (field.Oneof == nil || field.Oneof.Desc.IsSynthetic()) {
+		return value{kind: optionalValue, field: field}
+	}
+	return value{kind: scalarValue, field: field}
+}
+
+// scalarType returns the Go type of the given scalar field.
+func (gn *generator) scalarType(field *protogen.Field) string {
+	switch field.Desc.Kind() {
+	case protoreflect.BoolKind:
+		return "bool"
+	case protoreflect.EnumKind:
+		return gn.g.QualifiedGoIdent(field.Enum.GoIdent)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return "int32"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return "uint32"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return "int64"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "uint64"
+	case protoreflect.FloatKind:
+		return "float32"
+	case protoreflect.DoubleKind:
+		return "float64"
+	case protoreflect.StringKind:
+		return "string"
+	case protoreflect.BytesKind:
+		return "[]byte"
+	}
+	panic("unsupported field kind " + field.Desc.Kind().String())
+}
+
+// ident returns the qualified identifier of the given package.
+func (gn *generator) ident(importPath protogen.GoImportPath, name string) string {
+	return gn.g.QualifiedGoIdent(importPath.Ident(name))
+}
+
+// utils returns the qualified identifier of the fieldmask_utils package.
+func (gn *generator) utils(name string) string {
+	return gn.ident(fieldmaskUtilsPackage, name)
+}
+
+// mapKeyString returns the expression which formats the map key the way a FieldFilter expects it.
+func (gn *generator) mapKeyString(field *protogen.Field, key string) string {
+	keyField := field.Message.Fields[0]
+	if keyField.Desc.Kind() == protoreflect.StringKind {
+		return key
+	}
+	return gn.ident(fmtPackage, "Sprint") + "(" + key + ")"
+}
+
+// filterName returns the given name if the value uses its filter, "_" otherwise.
+func filterName(v value, name string) string {
+	if v.usesFilter() {
+		return name
+	}
+	return "_"
+}
+
+// isOneof returns true if the field is a member of a (non-synthetic) oneof.
+func isOneof(field *protogen.Field) bool {
+	return field.Oneof != nil && !field.Oneof.Desc.IsSynthetic()
+}
+
+func (gn *generator) message(message *protogen.Message) {
+	if message.Desc.IsMapEntry() {
+		return
+	}
+	for _, nested := range message.Messages {
+		gn.message(nested)
+	}
+	gn.copyMasked(message)
+	for _, oneof := range message.Oneofs {
+		if !oneof.Desc.IsSynthetic() {
+			gn.copyMaskedOneof(message, oneof)
+		}
+	}
+	gn.toMapMasked(message)
+	for _, oneof := range message.Oneofs {
+		if !oneof.Desc.IsSynthetic() {
+			gn.toMapMaskedOneof(message, oneof)
+		}
+	}
+}
+
+// copyMasked generates the CopyMasked method of the message.
+func (gn *generator) copyMasked(message *protogen.Message) {
+	g, name := gn.g, message.GoIdent.GoName
+	filterType := gn.utils("FieldFilter")
+	g.P("// CopyMasked copies the fields of m selected by the mask to dst the same way ", gn.utils("StructToStruct"),
+		" does.")
+	g.P("func (m *", name, ") CopyMasked(dst *", name, ", mask ", filterType, ") error {")
+	g.P("if m == nil {")
+	g.P("return ", gn.ident(errorsPackage, "New"), `("src must not be nil")`)
+	g.P("}")
+	g.P("if dst == nil {")
+	g.P("return ", gn.ident(errorsPackage, "New"), `("dst must not be nil")`)
+	g.P("}")
+	g.P("if mask.IsEmpty() {")
+	g.P("dst.unknownFields = m.unknownFields")
+	if message.Desc.ExtensionRanges().Len() > 0 {
+		g.P("dst.extensionFields = m.extensionFields")
+	}
+	for _, field := range message.Fields {
+		if isOneof(field) {
+			if field == field.Oneof.Fields[0] {
+				g.P("dst.", field.Oneof.GoName, " = m.", field.Oneof.GoName)
+			}
+			continue
+		}
+		g.P("dst.", field.GoName, " = m.", field.GoName)
+	}
+	g.P("return nil")
+	g.P("}")
+	for _, field := range message.Fields {
+		if isOneof(field) {
+			if field == field.Oneof.Fields[0] {
+				g.P("if err := m.copyMasked", field.Oneof.GoName, "(dst, mask); err != nil {")
+				g.P("return err")
+				g.P("}")
+			}
+			continue
+		}
+		gn.copyField(field)
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+// copyField generates the code which copies the field if the mask selects it.
+func (gn *generator) copyField(field *protogen.Field) {
+	g := gn.g
+	src, dst := "m."+field.GoName, "dst."+field.GoName
+	v := gn.newValue(field)
+	if field.Desc.IsList() || field.Desc.IsMap() {
+		g.P("if sub, ok := mask.Filter(", strconv.Quote(field.GoName), "); ok {")
+	} else {
+		g.P("if ", filterName(v, "sub"), ", ok := mask.Filter(", strconv.Quote(field.GoName), "); ok {")
+	}
+	switch {
+	case field.Desc.IsMap():
+		gn.copyMap(field, src, dst)
+	case field.Desc.IsList():
+		gn.copyList(v, src, dst)
+	default:
+		gn.copyValue(v, "sub", src, dst)
+	}
+	g.P("}")
+}
+
+// copyValue generates the code which copies the singular value from the src expression to the dst expression.
+func (gn *generator) copyValue(v value, filter, src, dst string) {
+	g := gn.g
+	switch v.kind {
+	case scalarValue:
+		g.P(dst, " = ", src)
+
+	case optionalValue:
+		g.P("if ", src, " == nil {")
+		g.P(dst, " = nil")
+		g.P("} else {")
+		g.P("if ", dst, " == nil {")
+		g.P(dst, " = new(", gn.goType(v), ")")
+		g.P("}")
+		g.P("*", dst, " = *", src)
+		g.P("}")
+
+	case bytesValue:
+		g.P("if ", src, " == nil {")
+		g.P(dst, " = nil")
+		g.P("} else if _, ok := ", gn.utils("ItemFilter"), "(", filter, "); ok {")
+		g.P(dst, " = append(", dst, "[:0], ", src, "...)")
+		g.P("}")
+
+	case messageValue, externalMessageValue:
+		g.P("if ", src, " == nil {")
+		g.P(dst, " = nil")
+		g.P("} else {")
+		g.P("if ", dst, " == nil {")
+		g.P(dst, " = new(", gn.goType(v), ")")
+		g.P("}")
+		if v.kind == messageValue {
+			g.P("if err := ", src, ".CopyMasked(", dst, ", ", filter, "); err != nil {")
+		} else {
+			g.P("if err := ", gn.utils("StructToStruct"), "(", filter, ", ", src, ", ", dst, "); err != nil {")
+		}
+		g.P("return err")
+		g.P("}")
+		g.P("}")
+
+	case anyValue:
+		g.P("if err := ", gn.utils("CopyAny"), "(", filter, ", ", src, ", &", dst, "); err != nil {")
+		g.P("return err")
+		g.P("}")
+	}
+}
+
+// copyList generates the code which copies the repeated field using the "sub" filter.
+func (gn *generator) copyList(item value, src, dst string) {
+	g := gn.g
+	g.P("if ", src, " == nil {")
+	g.P(dst, " = nil")
+	if item.kind == scalarValue {
+		g.P("} else if _, ok := ", gn.utils("ItemFilter"), "(sub); ok {")
+		g.P(dst, " = append(", dst, "[:0], ", src, "...)")
+		g.P("}")
+		return
+	}
+	g.P("} else if items, ok := ", gn.utils("ItemFilter"), "(sub); ok {")
+	g.P("for i, item := range ", src, " {")
+	g.P("if i == len(", dst, ") {")
+	g.P(dst, " = append(", dst, ", nil)")
+	g.P("}")
+	gn.copyValue(item, "items", "item", dst+"[i]")
+	g.P("}")
+	g.P(dst, " = ", dst, "[:len(", src, ")]")
+	g.P("}")
+}
+
+// copyMap generates the code which copies the map field using the "sub" filter.
+func (gn *generator) copyMap(field *protogen.Field, src, dst string) {
+	g := gn.g
+	v := gn.newValue(field.Message.Fields[1])
+	mapType := "map[" + gn.scalarType(field.Message.Fields[0]) + "]" + gn.fieldType(v)
+	g.P("if sub.IsEmpty() && ", src, " == nil {")
+	g.P(dst, " = nil")
+	g.P("} else {")
+	g.P("if sub.IsEmpty() {")
+	g.P(dst, " = make(", mapType, ", len(", src, "))")
+	g.P("}")
+	g.P("for k := range ", dst, " {")
+	g.P("if _, ok := ", gn.utils("MapKeyFilter"), "(sub, ", gn.mapKeyString(field, "k"), "); ok {")
+	g.P("if _, ok := ", src, "[k]; !ok {")
+	g.P("delete(", dst, ", k)")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("for k, v := range ", src, " {")
+	g.P(filterName(v, "valueFilter"), ", ok := ", gn.utils("MapKeyFilter"), "(sub, ", gn.mapKeyString(field, "k"), ")")
+	g.P("if !ok {")
+	g.P("continue")
+	g.P("}")
+	g.P("if ", dst, " == nil {")
+	g.P(dst, " = make(", mapType, ")")
+	g.P("}")
+	if v.kind == scalarValue {
+		g.P(dst, "[k] = v")
+	} else {
+		g.P("d := ", dst, "[k]")
+		gn.copyValue(v, "valueFilter", "v", "d")
+		g.P(dst, "[k] = d")
+	}
+	g.P("}")
+	g.P("}")
+}
+
+// copyMaskedOneof generates the method which copies the oneof field.
+func (gn *generator) copyMaskedOneof(message *protogen.Message, oneof *protogen.Oneof) {
+	g, name := gn.g, oneof.GoName
+	filterType := gn.utils("FieldFilter")
+	usesFilter := false
+	for _, member := range oneof.Fields {
+		usesFilter = usesFilter || gn.newValue(member).usesFilter()
+	}
+	memberFilter := "_"
+	if usesFilter {
+		memberFilter = "memberFilter"
+	}
+
+	g.P("// copyMasked", name, " copies the ", name, " oneof of m to dst, see CopyMasked.")
+	g.P("// The members may be selected by the mask either directly by their names or through the oneof field.")
+	g.P("func (m *", message.GoIdent.GoName, ") copyMasked", name, "(dst *", message.GoIdent.GoName, ", mask ",
+		filterType, ") error {")
+	g.P(`srcMember, dstMember := "", ""`)
+	for _, side := range []string{"src", "dst"} {
+		receiver := "m"
+		if side == "dst" {
+			receiver = "dst"
+		}
+		g.P("switch ", receiver, ".", name, ".(type) {")
+		for _, member := range oneof.Fields {
+			g.P("case *", g.QualifiedGoIdent(member.GoIdent), ":")
+			g.P(side, "Member = ", strconv.Quote(member.GoName))
+		}
+		g.P("}")
+	}
+	g.P("if (srcMember != \"\" && ", gn.utils("MentionsField"), "(mask, srcMember)) || (dstMember != \"\" && ",
+		gn.utils("MentionsField"), "(mask, dstMember)) {")
+	g.P(`if srcMember != "" {`)
+	g.P("if ", memberFilter, ", ok := ", gn.utils("OneofMemberFilter"), "(mask, ", strconv.Quote(name),
+		", srcMember); ok {")
+	g.P("switch s := m.", name, ".(type) {")
+	for _, member := range oneof.Fields {
+		wrapper := g.QualifiedGoIdent(member.GoIdent)
+		g.P("case *", wrapper, ":")
+		g.P("d, ok := dst.", name, ".(*", wrapper, ")")
+		g.P("if !ok {")
+		g.P("d = &", wrapper, "{}")
+		g.P("dst.", name, " = d")
+		g.P("}")
+		gn.copyValue(gn.newValue(member), "memberFilter", "s."+member.GoName, "d."+member.GoName)
+	}
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P("}")
+	g.P(`if dstMember != "" && dstMember != srcMember {`)
+	g.P("if _, ok := ", gn.utils("OneofMemberFilter"), "(mask, ", strconv.Quote(name), ", dstMember); ok {")
+	g.P("dst.", name, " = nil")
+	g.P("}")
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("sub, ok := mask.Filter(", strconv.Quote(name), ")")
+	g.P("if !ok {")
+	g.P("return nil")
+	g.P("}")
+	g.P("switch s := m.", name, ".(type) {")
+	g.P("case nil:")
+	g.P("dst.", name, " = nil")
+	for _, member := range oneof.Fields {
+		wrapper := g.QualifiedGoIdent(member.GoIdent)
+		v := gn.newValue(member)
+		g.P("case *", wrapper, ":")
+		g.P("if dst.", name, " == nil {")
+		g.P("dst.", name, " = &", wrapper, "{}")
+		g.P("}")
+		g.P("d, ok := dst.", name, ".(*", wrapper, ")")
+		g.P("if !ok {")
+		g.P("if _, ok := sub.Filter(", strconv.Quote(member.GoName), "); ok {")
+		g.P("return ", gn.ident(errorsPackage, "New"), "(", strconv.Quote("Can't set a value on a destination field "+
+			member.GoName), ")")
+		g.P("}")
+		g.P("} else if sub.IsEmpty() {")
+		g.P("*d = *s")
+		g.P("} else if ", filterName(v, "memberFilter"), ", ok := sub.Filter(", strconv.Quote(member.GoName), "); ok {")
+		gn.copyValue(v, "memberFilter", "s."+member.GoName, "d."+member.GoName)
+		g.P("}")
+	}
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+// toMapMasked generates the ToMapMasked method of the message.
+func (gn *generator) toMapMasked(message *protogen.Message) {
+	g, name := gn.g, message.GoIdent.GoName
+	g.P("// ToMapMasked copies the fields of m selected by the mask to the dst map the same way ",
+		gn.utils("StructToMap"), " does.")
+	g.P("func (m *", name, ") ToMapMasked(dst map[string]interface{}, mask ", gn.utils("FieldFilter"), ") error {")
+	g.P("if m == nil {")
+	g.P("return ", gn.ident(errorsPackage, "New"), `("src must not be nil")`)
+	g.P("}")
+	for _, field := range message.Fields {
+		if isOneof(field) {
+			if field == field.Oneof.Fields[0] {
+				g.P("if err := m.toMapMasked", field.Oneof.GoName, "(dst, mask); err != nil {")
+				g.P("return err")
+				g.P("}")
+			}
+			continue
+		}
+		gn.fieldToMap(field)
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
+
+// fieldToMap generates the code which copies the field to the "dst" map if the mask selects it.
+func (gn *generator) fieldToMap(field *protogen.Field) {
+	g := gn.g
+	src, key := "m."+field.GoName, strconv.Quote(field.GoName)
+	v := gn.newValue(field)
+	if field.Desc.IsList() || field.Desc.IsMap() {
+		g.P("if sub, ok := mask.Filter(", key, "); ok {")
+	} else {
+		g.P("if ", filterName(v, "sub"), ", ok := mask.Filter(", key, "); ok {")
+	}
+	switch {
+	case field.Desc.IsMap():
+		gn.mapToMap(field, src, key)
+	case field.Desc.IsList():
+		gn.listToMap(v, src, key)
+	default:
+		gn.valueToMap(v, "sub", src, "dst", key)
+	}
+	g.P("}")
+}
+
+// valueToMap generates the code which copies the singular value from the src expression to the dstMap under the key.
+func (gn *generator) valueToMap(v value, filter, src, dstMap, key string) {
+	g := gn.g
+	dst := dstMap + "[" + key + "]"
+	switch v.kind {
+	case scalarValue:
+		g.P(dst, " = ", src)
+
+	case optionalValue:
+		g.P("if ", src, " == nil {")
+		gn.clearMapValue(dstMap, key)
+		g.P("} else {")
+		g.P(dst, " = *", src)
+		g.P("}")
+
+	case bytesValue:
+		g.P("if _, ok := ", gn.utils("ItemFilter"), "(", filter, "); ok {")
+		g.P(dst, " = ", src)
+		g.P("} else if ", dst, " == nil {")
+		g.P(dst, " = []byte{}")
+		g.P("}")
+
+	default:
+		g.P("if ", src, " == nil {")
+		gn.clearMapValue(dstMap, key)
+		g.P("} else {")
+		g.P("nested, ok := ", dst, ".(map[string]interface{})")
+		g.P("if !ok {")
+		g.P("nested = make(map[string]interface{})")
+		g.P("}")
+		gn.messageToMap(v, filter, src, "nested")
+		g.P(dst, " = nested")
+		g.P("}")
+	}
+}
+
+// messageToMap generates the code which copies the message from the src expression to the dst map.
+func (gn *generator) messageToMap(v value, filter, src, dst string) {
+	g := gn.g
+	if v.kind == messageValue {
+		g.P("if err := ", src, ".ToMapMasked(", dst, ", ", filter, "); err != nil {")
+	} else {
+		g.P("if err := ", gn.utils("StructToMap"), "(", filter, ", ", src, ", ", dst, "); err != nil {")
+	}
+	g.P("return err")
+	g.P("}")
+}
+
+// clearMapValue generates the code which sets the nil value in the dstMap under the key. An existing value is deleted.
+func (gn *generator) clearMapValue(dstMap, key string) {
+	g := gn.g
+	g.P("if old, ok := ", dstMap, "[", key, "]; ok && old != nil {")
+	g.P("delete(", dstMap, ", ", key, ")")
+	g.P("} else {")
+	g.P(dstMap, "[", key, "] = nil")
+	g.P("}")
+}
+
+// listToMap generates the code which copies the repeated field to the "dst" map under the key using the "sub" filter.
+func (gn *generator) listToMap(item value, src, key string) {
+	g := gn.g
+	dst := "dst[" + key + "]"
+	switch {
+	case item.kind == scalarValue:
+		g.P("if _, ok := ", gn.utils("ItemFilter"), "(sub); ok {")
+		g.P(dst, " = ", src)
+		g.P("} else if ", dst, " == nil {")
+		g.P(dst, " = []", gn.goType(item), "{}")
+		g.P("}")
+
+	case item.kind == bytesValue:
+		// The existing items are kept, the new ones are appended.
+		g.P("if _, ok := ", gn.utils("ItemFilter"), "(sub); ok {")
+		g.P("d, ok := ", dst, ".([][]byte)")
+		g.P("if !ok {")
+		g.P("d = [][]byte{}")
+		g.P("}")
+		g.P("for i := len(d); i < len(", src, "); i++ {")
+		g.P("d = append(d, ", src, "[i])")
+		g.P("}")
+		g.P(dst, " = d[:len(", src, ")]")
+		g.P("} else if ", dst, " == nil {")
+		g.P(dst, " = [][]byte{}")
+		g.P("}")
+
+	default:
+		g.P("if items, ok := ", gn.utils("ItemFilter"), "(sub); ok {")
+		g.P("d, ok := ", dst, ".([]map[string]interface{})")
+		g.P("if !ok {")
+		g.P("d = []map[string]interface{}{}")
+		g.P("}")
+		g.P("for i, item := range ", src, " {")
+		g.P("if i == len(d) {")
+		g.P("d = append(d, make(map[string]interface{}))")
+		g.P("}")
+		gn.messageToMap(item, "items", "item", "d[i]")
+		g.P("}")
+		g.P(dst, " = d[:len(", src, ")]")
+		g.P("} else if ", dst, " == nil {")
+		g.P(dst, " = []map[string]interface{}{}")
+		g.P("}")
+	}
+}
+
+// mapToMap generates the code which copies the map field to the "dst" map under the key using the "sub" filter.
+func (gn *generator) mapToMap(field *protogen.Field, src, key string) {
+	g := gn.g
+	dst := "dst[" + key + "]"
+	v := gn.newValue(field.Message.Fields[1])
+	resultType := "map[" + gn.scalarType(field.Message.Fields[0]) + "]"
+	if v.isMessage() {
+		resultType += "map[string]interface{}"
+	} else {
+		resultType += gn.fieldType(v)
+	}
+	g.P("if sub.IsEmpty() {")
+	g.P(dst, " = ", src)
+	g.P("} else {")
+	g.P("result := make(", resultType, ")")
+	g.P("if existing, ok := ", dst, ".(", resultType, "); ok {")
+	g.P("for k, v := range existing {")
+	g.P("result[k] = v")
+	g.P("}")
+	g.P("}")
+	g.P("for k := range result {")
+	g.P("if _, ok := ", gn.utils("MapKeyFilter"), "(sub, ", gn.mapKeyString(field, "k"), "); ok {")
+	g.P("if _, ok := ", src, "[k]; !ok {")
+	g.P("delete(result, k)")
+	g.P("}")
+	g.P("}")
+	g.P("}")
+	g.P("for k, v := range ", src, " {")
+	g.P(filterName(v, "valueFilter"), ", ok := ", gn.utils("MapKeyFilter"), "(sub, ", gn.mapKeyString(field, "k"), ")")
+	g.P("if !ok {")
+	g.P("continue")
+	g.P("}")
+	switch {
+	case v.kind == scalarValue:
+		g.P("result[k] = v")
+
+	case v.kind == bytesValue:
+		g.P("if _, ok := ", gn.utils("ItemFilter"), "(valueFilter); ok {")
+		g.P("result[k] = v")
+		g.P("} else if _, ok := result[k]; !ok {")
+		g.P("result[k] = []byte{}")
+		g.P("}")
+
+	default:
+		g.P("if v == nil {")
+		g.P("result[k] = nil")
+		g.P("continue")
+		g.P("}")
+		g.P("d := result[k]")
+		g.P("if d == nil {")
+		g.P("d = make(map[string]interface{})")
+		g.P("}")
+		gn.messageToMap(v, "valueFilter", "v", "d")
+		g.P("result[k] = d")
+	}
+	g.P("}")
+	g.P(dst, " = result")
+	g.P("}")
+}
+
+// toMapMaskedOneof generates the method which copies the oneof field to the map.
+func (gn *generator) toMapMaskedOneof(message *protogen.Message, oneof *protogen.Oneof) {
+	g, name := gn.g, oneof.GoName
+	g.P("// toMapMasked", name, " copies the ", name, " oneof of m to the dst map, see ToMapMasked.")
+	g.P("// The members selected by the mask directly by their names are copied under their own keys.")
+	g.P("func (m *", message.GoIdent.GoName, ") toMapMasked", name, "(dst map[string]interface{}, mask ",
+		gn.utils("FieldFilter"), ") error {")
+	g.P("if ")
+	for i, member := range oneof.Fields {
+		suffix := " ||"
+		if i == len(oneof.Fields)-1 {
+			suffix = " {"
+		}
+		g.P(gn.utils("MentionsField"), "(mask, ", strconv.Quote(member.GoName), ")", suffix)
+	}
+	for _, member := range oneof.Fields {
+		v := gn.newValue(member)
+		key := strconv.Quote(member.GoName)
+		g.P("if ", filterName(v, "memberFilter"), ", ok := ", gn.utils("OneofMemberFilter"), "(mask, ",
+			strconv.Quote(name), ", ", key, "); ok {")
+		g.P("if s, ok := m.", name, ".(*", g.QualifiedGoIdent(member.GoIdent), "); ok {")
+		gn.valueToMap(v, "memberFilter", "s."+member.GoName, "dst", key)
+		g.P("} else {")
+		g.P("dst[", key, "] = nil")
+		g.P("}")
+		g.P("}")
+	}
+	g.P("return nil")
+	g.P("}")
+	g.P()
+	g.P("sub, ok := mask.Filter(", strconv.Quote(name), ")")
+	g.P("if !ok {")
+	g.P("return nil")
+	g.P("}")
+	g.P("switch s := m.", name, ".(type) {")
+	g.P("case nil:")
+	gn.clearMapValue("dst", strconv.Quote(name))
+	for _, member := range oneof.Fields {
+		v := gn.newValue(member)
+		g.P("case *", g.QualifiedGoIdent(member.GoIdent), ":")
+		g.P("d, ok := dst[", strconv.Quote(name), "].(map[string]interface{})")
+		g.P("if !ok {")
+		g.P("d = make(map[string]interface{})")
+		g.P("}")
+		g.P("if ", filterName(v, "memberFilter"), ", ok := sub.Filter(", strconv.Quote(member.GoName), "); ok {")
+		gn.valueToMap(v, "memberFilter", "s."+member.GoName, "d", strconv.Quote(member.GoName))
+		g.P("}")
+		g.P("dst[", strconv.Quote(name), "] = d")
+	}
+	g.P("}")
+	g.P("return nil")
+	g.P("}")
+	g.P()
+}
@@ -0,0 +1,29 @@
+// Command protoc-gen-go-fieldmask is a protoc plugin which generates the CopyMasked and ToMapMasked methods for every
+// message of the given proto files:
+//
+//	func (m *User) CopyMasked(dst *User, mask fieldmask_utils.FieldFilter) error
+//	func (m *User) ToMapMasked(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error
+//
+// The methods have the same semantics as fieldmask_utils.StructToStruct and fieldmask_utils.StructToMap with the default
+// options, but they do not use reflection except for the fields of the messages that are not generated with this plugin
+// in the same run (e.g. the well-known types) and the messages packed into google.protobuf.Any.
+//
+// The plugin is used along with protoc-gen-go:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-fieldmask_out=. --go-fieldmask_opt=paths=source_relative foo.proto
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if f.Generate {
+				generateFile(gen, f)
+			}
+		}
+		return nil
+	})
+}
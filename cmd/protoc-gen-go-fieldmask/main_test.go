@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+var update = flag.Bool("update", false, "update the generated testproto file")
+
+const goldenFile = "../../testproto/test_fieldmask.pb.go"
+
+// request returns the CodeGeneratorRequest for the given file which protoc would send to the plugin.
+func request(file protoreflect.FileDescriptor) *pluginpb.CodeGeneratorRequest {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.Path()},
+		Parameter:      proto.String("paths=source_relative"),
+	}
+	imports := file.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(imports.Get(i).FileDescriptor))
+	}
+	req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(file))
+	return req
+}
+
+func TestGenerateFile(t *testing.T) {
+	gen, err := protogen.Options{}.New(request(testproto.File_test_proto))
+	require.NoError(t, err)
+	for _, f := range gen.Files {
+		if f.Generate {
+			generateFile(gen, f)
+		}
+	}
+	resp := gen.Response()
+	require.Nil(t, resp.Error)
+	require.Len(t, resp.File, 1)
+	assert.Equal(t, "test_fieldmask.pb.go", resp.File[0].GetName())
+
+	if *update {
+		require.NoError(t, os.WriteFile(goldenFile, []byte(resp.File[0].GetContent()), 0644))
+	}
+	golden, err := os.ReadFile(goldenFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), resp.File[0].GetContent(), "run go test with -update to regenerate the file")
+}
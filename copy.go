@@ -2,6 +2,7 @@
 package fieldmask_utils
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 
@@ -14,11 +15,18 @@ import (
 // Only the fields where FieldFilter returns true will be copied to `dst`.
 // `src` and `dst` must be coherent in terms of the field names, but it is not required for them to be of the same type.
 // Unexported fields are copied only if the corresponding struct filter is empty and `dst` is assignable to `src`.
+// Unless the WithDeepCopy option is set `dst` may share memory with `src`: a struct selected with an empty filter is
+// assigned as a whole (so its pointers, slices and maps are shared), a pointer field in `dst` is set to the address of
+// the corresponding non-pointer field in `src`, the values stored in the interfaces of a map are shared and so is an Any
+// message which is not unmarshaled. Other pointers, slices and maps are allocated in `dst` (or reused if they exist).
 func StructToStruct(filter FieldFilter, src, dst interface{}, userOpts ...Option) error {
 	opts := newDefaultOptions()
 	for _, o := range userOpts {
 		o(opts)
 	}
+	if opts.listRulesErr != nil {
+		return opts.listRulesErr
+	}
 
 	dstVal := reflect.ValueOf(dst)
 	if dstVal.Kind() != reflect.Ptr {
@@ -32,7 +40,10 @@ func StructToStruct(filter FieldFilter, src, dst interface{}, userOpts ...Option
 	if dstVal.Kind() != reflect.Struct {
 		return errors.Errorf("dst kind must be a struct, %s given", dstVal.Kind())
 	}
-	return structToStruct(filter, &srcVal, &dstVal, opts)
+	if err := structToStruct(filter, &srcVal, &dstVal, opts); err != nil {
+		return err
+	}
+	return checkStrictPaths(filter, opts)
 }
 
 func ensureCompatible(src, dst *reflect.Value) error {
@@ -51,6 +62,9 @@ func ensureCompatible(src, dst *reflect.Value) error {
 }
 
 func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *options) error {
+	// The list key declared by the tag only applies to the value of the field itself.
+	listKey := userOptions.listKey
+	userOptions.listKey = ""
 	if err := ensureCompatible(src, dst); err != nil {
 		// incompatible, try using converters:
 		converted := false
@@ -79,7 +93,11 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 	switch src.Kind() {
 	case reflect.Struct:
 		if dst.CanSet() && dst.Type().AssignableTo(src.Type()) && filter.IsEmpty() {
-			dst.Set(*src)
+			if userOptions.DeepCopy {
+				dst.Set(deepCopy(*src))
+			} else {
+				dst.Set(*src)
+			}
 			return nil
 		}
 
@@ -91,10 +109,21 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 			dst = &v
 		}
 
-		for i := 0; i < src.NumField(); i++ {
-			srcType := src.Type()
-			srcName := fieldName(userOptions.SrcTag, srcType.Field(i))
-			dstName := fieldName(userOptions.DstTag, srcType.Field(i))
+		if userOptions.pathTracker != nil {
+			userOptions.pathTracker.visitStruct(filter, src.Type(), userOptions)
+		}
+		for _, f := range copyFieldsFor(filter, src.Type(), dst.Type(), userOptions) {
+			srcName, dstName := f.srcName, f.dstName
+
+			if f.oneof {
+				handled, err := oneofToOneof(filter, srcName, src.Field(f.index), fieldByIndex(*dst, f.dstIndex), userOptions)
+				if err != nil {
+					return err
+				}
+				if handled {
+					continue
+				}
+			}
 
 			subFilter, ok := filter.Filter(srcName)
 			if !ok {
@@ -102,17 +131,21 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 				continue
 			}
 
-			srcField := src.Field(i)
+			srcField := src.Field(f.index)
 			if !srcField.CanInterface() {
 				continue
 			}
 
-			dstField := dst.FieldByName(dstName)
+			dstField := fieldByIndex(*dst, f.dstIndex)
 			if !dstField.CanSet() {
 				return errors.Errorf("Can't set a value on a destination field %s", dstName)
 			}
 
-			if err := structToStruct(subFilter, &srcField, &dstField, userOptions); err != nil {
+			userOptions.pushPath(srcName)
+			userOptions.listKey = f.listKey
+			err := structToStruct(subFilter, &srcField, &dstField, userOptions)
+			userOptions.popPath()
+			if err != nil {
 				return err
 			}
 		}
@@ -136,7 +169,11 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 
 			// If subfilter is empty then copy the entire any without any unmarshalling.
 			if filter.IsEmpty() && !userOptions.UnmarshalAllAny {
-				dst.Set(*src)
+				if userOptions.DeepCopy {
+					dst.Set(deepCopy(*src))
+				} else {
+					dst.Set(*src)
+				}
 				break
 			}
 
@@ -173,6 +210,8 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 			dstElem = dst.Elem()
 		}
 
+		// The pointed to value is still the value of the field, e.g. a *[]T slice.
+		userOptions.listKey = listKey
 		if err := structToStruct(filter, &srcElem, &dstElem, userOptions); err != nil {
 			return err
 		}
@@ -197,14 +236,35 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 		}
 
 	case reflect.Slice:
+		strategy, key := userOptions.listStrategy(listKey)
 		if src.IsNil() {
-			// If the source slice is nil the dst slice is set to nil too.
-			dst.Set(reflect.Zero(dst.Type()))
+			// If the source slice is nil the dst slice is set to nil too (unless the items are appended).
+			if strategy != Append {
+				dst.Set(reflect.Zero(dst.Type()))
+			}
 			break
 		}
 
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			break
+		}
+		if key != "" {
+			if err := mergeByKey(itemsFilter, key, src, dst, userOptions); err != nil {
+				return err
+			}
+			break
+		}
 		dstLen := dst.Len()
 		srcLen := userOptions.CopyListSize(src)
+		switch strategy {
+		case Replace:
+			dst.Set(reflect.MakeSlice(dst.Type(), 0, srcLen))
+			dstLen = 0
+		case Append:
+			// The src items are copied to the new items.
+			dstLen = 0
+		}
 
 		for i := 0; i < srcLen; i++ {
 			srcItem := src.Index(i)
@@ -217,7 +277,7 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 				dstItem = reflect.New(dst.Type().Elem()).Elem()
 			}
 
-			if err := structToStruct(filter, &srcItem, &dstItem, userOptions); err != nil {
+			if err := structToStruct(itemsFilter, &srcItem, &dstItem, userOptions); err != nil {
 				return err
 			}
 
@@ -226,11 +286,15 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 				dst.Set(reflect.Append(*dst, dstItem))
 			}
 		}
-		if dstLen > srcLen {
+		if strategy == MergeByIndex && dstLen > srcLen {
 			dst.SetLen(srcLen)
 		}
 
 	case reflect.Array:
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			break
+		}
 		dstLen := dst.Len()
 		srcLen := userOptions.CopyListSize(src)
 		if dstLen < srcLen {
@@ -239,16 +303,27 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 		for i := 0; i < srcLen; i++ {
 			srcItem := src.Index(i)
 			dstItem := dst.Index(i)
-			if err := structToStruct(filter, &srcItem, &dstItem, userOptions); err != nil {
+			if err := structToStruct(itemsFilter, &srcItem, &dstItem, userOptions); err != nil {
 				return errors.WithStack(err)
 			}
 		}
 
+	case reflect.Map:
+		if err := mapToMap(filter, src, dst, userOptions); err != nil {
+			return err
+		}
+
 	default:
 		if !dst.CanSet() {
 			return errors.Errorf("dst %s, %s is not settable", dst, dst.Type())
 		}
 		if dst.Kind() == reflect.Ptr {
+			if userOptions.DeepCopy {
+				ptr := reflect.New(src.Type())
+				ptr.Elem().Set(*src)
+				dst.Set(ptr)
+				break
+			}
 			if !src.CanAddr() {
 				return errors.Errorf("src %s, %s is not addressable", src, src.Type())
 			}
@@ -261,6 +336,118 @@ func structToStruct(filter FieldFilter, src, dst *reflect.Value, userOptions *op
 	return nil
 }
 
+// mapToMap copies the `src` map to the `dst` map.
+// If the filter is empty the `dst` map is replaced with a copy of the `src` map. Otherwise, the filter selects the map
+// entries by their keys (or by the Wildcard which selects all of them): selected entries are inserted or overwritten,
+// selected entries missing in `src` are deleted from `dst`, other entries of `dst` are left intact.
+// Map values are copied using the corresponding sub-filters, so that `dst` does not share pointers, slices and maps
+// with `src` (values stored in interfaces are the exception).
+func mapToMap(filter FieldFilter, src, dst *reflect.Value, userOptions *options) error {
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		v := dst.Elem()
+		dst = &v
+	}
+	dstType, srcType := dst.Type(), src.Type()
+
+	if filter.IsEmpty() {
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dstType))
+			return nil
+		}
+		dst.Set(reflect.MakeMapWithSize(dstType, src.Len()))
+	}
+
+	for _, dstKey := range dst.MapKeys() {
+		if _, ok := mapKeyFilter(filter, mapKeyString(dstKey)); !ok {
+			continue
+		}
+		srcKey, err := convertMapKey(dstKey, srcType.Key())
+		if err != nil {
+			return err
+		}
+		if !src.MapIndex(srcKey).IsValid() {
+			dst.SetMapIndex(dstKey, reflect.Value{})
+		}
+	}
+
+	for _, srcKey := range src.MapKeys() {
+		subFilter, ok := mapKeyFilter(filter, mapKeyString(srcKey))
+		if !ok {
+			continue
+		}
+		dstKey, err := convertMapKey(srcKey, dstType.Key())
+		if err != nil {
+			return err
+		}
+		// Map items are not addressable: copy them to the new values first.
+		srcItem := reflect.New(srcType.Elem()).Elem()
+		srcItem.Set(src.MapIndex(srcKey))
+		dstItem := reflect.New(dstType.Elem()).Elem()
+		if existingItem := dst.MapIndex(dstKey); existingItem.IsValid() {
+			dstItem.Set(existingItem)
+		}
+		if srcItem.Kind() == reflect.Interface && subFilter.IsEmpty() && srcItem.Type().AssignableTo(dstItem.Type()) {
+			if userOptions.DeepCopy {
+				srcItem = deepCopy(srcItem)
+			}
+			dstItem.Set(srcItem)
+		} else {
+			userOptions.pushPath(mapKeyString(srcKey))
+			err := structToStruct(subFilter, &srcItem, &dstItem, userOptions)
+			userOptions.popPath()
+			if err != nil {
+				return err
+			}
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dstType))
+		}
+		dst.SetMapIndex(dstKey, dstItem)
+	}
+	return nil
+}
+
+// mapKeyFilter returns the FieldFilter for the map entry with the given key.
+func mapKeyFilter(filter FieldFilter, key string) (FieldFilter, bool) {
+	if filter.IsEmpty() {
+		return filter, true
+	}
+	return filter.Filter(key)
+}
+
+// itemFilter returns the FieldFilter for the items of a list. The Wildcard entry of a list filter selects the items
+// explicitly: "Images{*{OriginalUrl}}" is the same as "Images{OriginalUrl}".
+// Result is false if the items are not supposed to be copied.
+func itemFilter(filter FieldFilter) (FieldFilter, bool) {
+	if !mentionsField(filter, Wildcard) {
+		return filter, true
+	}
+	subFilter, ok := filter.Filter(Wildcard)
+	return subFilter, ok && subFilter != nil
+}
+
+// mapKeyString returns the string representation of the map key which is used as a field name in a FieldFilter.
+func mapKeyString(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// convertMapKey converts the given map key to the given type.
+func convertMapKey(key reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if key.Type() == t {
+		return key, nil
+	}
+	if key.Kind() != t.Kind() || !key.Type().ConvertibleTo(t) {
+		return key, errors.Errorf("map key type %s is not compatible with %s", key.Type(), t)
+	}
+	return key.Convert(t), nil
+}
+
 // options are used in StructToStruct and StructToMap functions to modify the copying behavior.
 type options struct {
 	// DstTag can be used to customize the dst field name according to the field's tag, i.g. json.
@@ -293,6 +480,36 @@ type options struct {
 	// If a converter returns an error that error is propagated to the
 	// initial call of StructToStruct.
 	ConverterHooks []func(src, dst *reflect.Value) (interface{}, error)
+
+	// StrictPaths is used to return an error if some of the filter entries have not been matched by any source field.
+	StrictPaths bool
+
+	// pathTracker records the matched filter entries if StrictPaths is set.
+	pathTracker *pathTracker
+
+	// Filter restricts the fields DiffMask and MaskFromNonZero check.
+	Filter FieldFilter
+
+	// DeepCopy is used to make sure that the values copied to dst never share memory with src, see WithDeepCopy.
+	DeepCopy bool
+
+	// ListStrategy defines how the slices are copied unless they have a strategy of their own, see WithListStrategy.
+	ListStrategy ListStrategy
+
+	// listStrategies holds the strategies of the slices at the particular paths.
+	listStrategies []listStrategyRule
+
+	// listRulesErr is the first error of the WithListStrategy and WithListKey options, returned by the copy functions.
+	listRulesErr error
+
+	// path is the path of the value being copied. Only tracked if listStrategies are set.
+	path []string
+
+	// listKey is the key declared by the tag of the struct field being copied, see WithListKey.
+	listKey string
+
+	// plan holds the fields resolved in advance by Compile.
+	plan *Plan
 }
 
 // mapVisitor is called for every filtered field in structToMap.
@@ -349,6 +566,40 @@ func WithConverterHook(converter func(src, dst *reflect.Value) (interface{}, err
 	}
 }
 
+// WithStrictPaths sets an option that makes StructToStruct, StructToMap and ProtoToProto return an *UnknownPathsError
+// listing every filter entry that has not been matched by a source field. Only the filters applied to structs (or
+// messages) are checked: e.g. the sub-filter of a list is checked against the fields of its items (which are matched
+// if any of the items has them), but not if the list is empty. Map keys and the entries of the Any messages that are
+// not unmarshaled are not checked. The entries of a non-empty sub-filter of a field without fields of its own (e.g. a
+// string) are never matched: "Name{X}" reports "Name.X".
+// A sub-filter shared by several entries of the filter is checked as a whole: its entries are matched by the fields of
+// any of the structs it is applied to.
+// The fields matched by the filter are copied even if an error is returned.
+func WithStrictPaths() Option {
+	return func(o *options) {
+		o.StrictPaths = true
+		o.pathTracker = newPathTracker()
+	}
+}
+
+// WithDeepCopy sets an option that makes StructToStruct and StructToMap allocate every pointer, slice, map and Any
+// message they copy to dst, so that modifying src after a copy never modifies dst and vice versa.
+// Without it dst may share memory with src (see StructToStruct and StructToMap), which is cheaper and is fine as long
+// as src is not modified afterwards. The unexported fields of the structs other than protobuf messages are still
+// copied as is (protobuf messages are copied with proto.Clone).
+func WithDeepCopy() Option {
+	return func(o *options) {
+		o.DeepCopy = true
+	}
+}
+
+// WithFilter sets an option that restricts DiffMask and MaskFromNonZero to the fields selected by the given filter.
+func WithFilter(filter FieldFilter) Option {
+	return func(o *options) {
+		o.Filter = filter
+	}
+}
+
 func newDefaultOptions() *options {
 	// set default CopyListSize is func which return src.Len()
 	return &options{
@@ -376,13 +627,20 @@ func fieldName(tag string, f reflect.StructField) string {
 // StructToMap copies `src` struct to the `dst` map.
 // Behavior is similar to `StructToStruct`.
 // Arrays in the non-empty dst are converted to slices.
+// Unless the WithDeepCopy option is set the slices of the primitive values and the maps selected with an empty filter
+// are shared with `src`.
 func StructToMap(filter FieldFilter, src interface{}, dst map[string]interface{}, userOpts ...Option) error {
 	opts := newDefaultOptions()
 	for _, o := range userOpts {
 		o(opts)
 	}
-	_, err := structToMap(filter, reflect.ValueOf(src), reflect.ValueOf(dst), opts)
-	return err
+	if opts.listRulesErr != nil {
+		return opts.listRulesErr
+	}
+	if _, err := structToMap(filter, reflect.ValueOf(src), reflect.ValueOf(dst), opts); err != nil {
+		return err
+	}
+	return checkStrictPaths(filter, opts)
 }
 
 func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *options) (reflect.Value, error) {
@@ -392,6 +650,9 @@ func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *option
 			return dst, errors.Errorf("incompatible destination kind: %s, expected map", dst.Kind())
 		}
 		srcType := src.Type()
+		if userOptions.pathTracker != nil {
+			userOptions.pathTracker.visitStruct(filter, srcType, userOptions)
+		}
 		for i := 0; i < src.NumField(); i++ {
 			srcName := fieldName(userOptions.SrcTag, srcType.Field(i))
 			if !isExported(srcType.Field(i)) {
@@ -399,45 +660,28 @@ func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *option
 				continue
 			}
 
-			subFilter, ok := filter.Filter(srcName)
-			if !ok {
-				// Skip this field.
-				continue
-			}
-			srcField := indirect(src.Field(i))
-			dstName := fieldName(userOptions.DstTag, srcType.Field(i))
-			mapValue := indirect(dst.MapIndex(reflect.ValueOf(dstName)))
-			if !mapValue.IsValid() {
-				if srcField.IsValid() {
-					mapValue = newValue(srcField.Type())
-				} else {
-					dstMap := dst.Interface().(map[string]interface{})
-					dstMap[dstName] = nil
-					continue
+			if isOneof(srcType.Field(i)) {
+				handled, err := oneofToMap(filter, src, dst, srcType.Field(i), userOptions)
+				if err != nil {
+					return dst, err
 				}
-			}
-			if userOptions.MapVisitor != nil {
-				result := userOptions.MapVisitor(filter, src, mapValue, srcName, dstName, srcField)
-				if result.UpdatedDst != nil {
-					mapValue = *result.UpdatedDst
-
-				}
-				if result.SkipToNext {
-					if result.UpdatedDst != nil {
-						dst.SetMapIndex(reflect.ValueOf(dstName), mapValue)
-					}
+				if handled {
 					continue
 				}
 			}
-			if isPrimitive(mapValue.Kind()) {
-				dst.SetMapIndex(reflect.ValueOf(dstName), srcField)
+
+			subFilter, ok := filter.Filter(srcName)
+			if !ok {
+				// Skip this field.
 				continue
 			}
-			var err error
-			if mapValue, err = structToMap(subFilter, srcField, mapValue, userOptions); err != nil {
+			dstName := fieldName(userOptions.DstTag, srcType.Field(i))
+			userOptions.pushPath(srcName)
+			err := fieldToMap(filter, subFilter, src, dst, srcName, dstName, src.Field(i), userOptions)
+			userOptions.popPath()
+			if err != nil {
 				return dst, err
 			}
-			dst.SetMapIndex(reflect.ValueOf(dstName), mapValue)
 		}
 
 	case reflect.Ptr:
@@ -465,16 +709,28 @@ func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *option
 		if dstKind := dst.Kind(); dstKind != reflect.Slice && dstKind != reflect.Array {
 			return dst, errors.Errorf("incompatible destination kind: %s, expected slice", dst.Kind())
 		}
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			break
+		}
 		itemType := src.Type().Elem()
 		desiredDstLen := userOptions.CopyListSize(&src)
 		itemKind := itemType.Kind()
+		strategy, _ := userOptions.listStrategy("")
 		if isPrimitive(itemKind) {
+			existing := dst
 			// Handle this array/slice as a regular non-nested data structure: copy it entirely to dst.
 			if desiredDstLen < src.Len() {
 				dst = src.Slice(0, desiredDstLen)
 			} else {
 				dst = src
 			}
+			if strategy == Append && existing.Kind() == reflect.Slice && existing.Type() == dst.Type() {
+				dst = reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, existing.Len()+dst.Len()),
+					existing), dst)
+			} else if userOptions.DeepCopy {
+				dst = deepCopy(dst)
+			}
 		} else {
 			if dst.Kind() == reflect.Array {
 				// Convert the array to a slice.
@@ -484,28 +740,50 @@ func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *option
 				}
 				dst = sliceDst
 			}
+			existingLen := dst.Len()
+			switch strategy {
+			case Replace:
+				dst = newValue(src.Type())
+				existingLen = 0
+			case Append:
+				// The src items are copied to the new items.
+				existingLen = 0
+			}
 			var err error
 			for i := 0; i < desiredDstLen; i++ {
 				itemExists := false
 				var subDst reflect.Value
-				if i < dst.Len() {
+				if i < existingLen {
 					subDst = dst.Index(i)
 					itemExists = true
 				} else {
 					subDst = newValue(itemType)
 				}
-				if subDst, err = structToMap(filter, src.Index(i), subDst, userOptions); err != nil {
+				if subDst, err = structToMap(itemsFilter, src.Index(i), subDst, userOptions); err != nil {
 					return subDst, err
 				}
 				if !itemExists {
 					dst = reflect.Append(dst, subDst)
 				}
 			}
-			if desiredDstLen < dst.Len() {
+			if strategy == MergeByIndex && desiredDstLen < dst.Len() {
 				dst = dst.Slice(0, desiredDstLen)
 			}
 		}
 
+	case reflect.Map:
+		if filter.IsEmpty() {
+			dst = src
+			if userOptions.DeepCopy {
+				dst = deepCopy(src)
+			}
+			break
+		}
+		var err error
+		if dst, err = mapToMapValue(filter, src, dst, userOptions); err != nil {
+			return dst, err
+		}
+
 	case reflect.Invalid:
 		dst.Set(reflect.ValueOf(nil))
 
@@ -515,6 +793,98 @@ func structToMap(filter FieldFilter, src, dst reflect.Value, userOptions *option
 	return dst, nil
 }
 
+// mapToMapValue copies the entries of the `src` map selected by the filter to a copy of the `dst` map, see mapToMap.
+// Values of the non-primitive types are converted the same way struct fields are.
+func mapToMapValue(filter FieldFilter, src, dst reflect.Value, userOptions *options) (reflect.Value, error) {
+	itemType := src.Type().Elem()
+	resultItemType := itemType
+	if !isPrimitive(itemType.Kind()) {
+		resultItemType = newValue(itemType).Type()
+	}
+	result := reflect.MakeMap(reflect.MapOf(src.Type().Key(), resultItemType))
+	if dst.IsValid() && dst.Kind() == reflect.Map && dst.Type().Key() == src.Type().Key() &&
+		dst.Type().Elem().AssignableTo(resultItemType) {
+		// Existing dst map is copied rather than updated in place as it may be shared with src.
+		for _, key := range dst.MapKeys() {
+			result.SetMapIndex(key, dst.MapIndex(key))
+		}
+	}
+
+	for _, key := range result.MapKeys() {
+		if _, ok := mapKeyFilter(filter, mapKeyString(key)); ok && !src.MapIndex(key).IsValid() {
+			result.SetMapIndex(key, reflect.Value{})
+		}
+	}
+
+	for _, key := range src.MapKeys() {
+		subFilter, ok := mapKeyFilter(filter, mapKeyString(key))
+		if !ok {
+			continue
+		}
+		srcItem := src.MapIndex(key)
+		if isPrimitive(itemType.Kind()) {
+			result.SetMapIndex(key, srcItem)
+			continue
+		}
+		if !indirect(srcItem).IsValid() {
+			result.SetMapIndex(key, reflect.Zero(resultItemType))
+			continue
+		}
+		dstItem := result.MapIndex(key)
+		if !dstItem.IsValid() || dstItem.Type() != resultItemType {
+			dstItem = newValue(itemType)
+		}
+		var err error
+		userOptions.pushPath(mapKeyString(key))
+		dstItem, err = structToMap(subFilter, srcItem, dstItem, userOptions)
+		userOptions.popPath()
+		if err != nil {
+			return dst, err
+		}
+		result.SetMapIndex(key, dstItem)
+	}
+	return result, nil
+}
+
+// fieldToMap copies a single field of the `src` struct to the `dst` map under the `dstName` key.
+func fieldToMap(filter, subFilter FieldFilter, src, dst reflect.Value, srcName, dstName string, srcField reflect.Value,
+	userOptions *options) error {
+	srcField = indirect(srcField)
+	mapValue := indirect(dst.MapIndex(reflect.ValueOf(dstName)))
+	if !mapValue.IsValid() {
+		if srcField.IsValid() {
+			mapValue = newValue(srcField.Type())
+		} else {
+			dstMap := dst.Interface().(map[string]interface{})
+			dstMap[dstName] = nil
+			return nil
+		}
+	}
+	if userOptions.MapVisitor != nil {
+		result := userOptions.MapVisitor(filter, src, mapValue, srcName, dstName, srcField)
+		if result.UpdatedDst != nil {
+			mapValue = *result.UpdatedDst
+
+		}
+		if result.SkipToNext {
+			if result.UpdatedDst != nil {
+				dst.SetMapIndex(reflect.ValueOf(dstName), mapValue)
+			}
+			return nil
+		}
+	}
+	if isPrimitive(mapValue.Kind()) {
+		dst.SetMapIndex(reflect.ValueOf(dstName), srcField)
+		return nil
+	}
+	var err error
+	if mapValue, err = structToMap(subFilter, srcField, mapValue, userOptions); err != nil {
+		return err
+	}
+	dst.SetMapIndex(reflect.ValueOf(dstName), mapValue)
+	return nil
+}
+
 func indirect(v reflect.Value) reflect.Value {
 	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
 		v = v.Elem()
@@ -0,0 +1,365 @@
+package fieldmask_utils
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ProtoToProto copies the fields of the `src` message selected by the filter to the `dst` message of the same type.
+// Unlike StructToStruct it uses the protobuf reflection (protoreflect) rather than the Go one, so that it works for
+// any proto.Message implementation including dynamicpb.Message and it never touches the internal fields of the
+// generated structs.
+// The fields are selected by their Go names as generated by protoc-gen-go (e.g. "OriginalUrl" for "original_url"), so
+// the same filter can be used with both functions. The oneof members are selected either directly by their names or
+// through the oneof field.
+// A selected field which is not set in `src` is cleared in `dst`, so the presence of the fields is copied as well.
+// The values are never shared with `src`. The unknown fields (and the extensions) are copied when the filter of the
+// message is empty.
+// The messages packed into google.protobuf.Any are resolved with protoregistry.GlobalTypes.
+// Only the UnmarshalAllAny and StrictPaths options apply, the options related to Go structs are ignored.
+func ProtoToProto(filter FieldFilter, src, dst proto.Message, userOpts ...Option) error {
+	opts := newDefaultOptions()
+	for _, o := range userOpts {
+		o(opts)
+	}
+	if src == nil || dst == nil {
+		return errors.New("src and dst must not be nil")
+	}
+	srcMsg, dstMsg := src.ProtoReflect(), dst.ProtoReflect()
+	if !dstMsg.IsValid() {
+		return errors.New("dst must not be a nil message")
+	}
+	if srcMsg.Descriptor().FullName() != dstMsg.Descriptor().FullName() {
+		return errors.Errorf("src type %s differs from dst type %s", srcMsg.Descriptor().FullName(),
+			dstMsg.Descriptor().FullName())
+	}
+	if err := protoMessageToMessage(filter, srcMsg, dstMsg, opts); err != nil {
+		return err
+	}
+	return checkStrictPaths(filter, opts)
+}
+
+// protoMessageToMessage copies the `src` message to the `dst` message of the same type.
+// The fields of `src` and `dst` are matched by their numbers, so that the messages may have different descriptors
+// (e.g. a dynamic message built from a copy of the descriptor of a generated one).
+func protoMessageToMessage(filter FieldFilter, src, dst protoreflect.Message, userOptions *options) error {
+	if dst.Descriptor().FullName() == anyMessageName {
+		return protoAnyToAny(filter, src, dst, userOptions)
+	}
+	if userOptions.pathTracker != nil {
+		userOptions.pathTracker.visitMessage(filter, dst.Descriptor())
+	}
+	srcFields, dstFields := src.Descriptor().Fields(), dst.Descriptor().Fields()
+	for i := 0; i < dstFields.Len(); i++ {
+		dstFd := dstFields.Get(i)
+		if od := dstFd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			if dstFd == od.Fields().Get(0) {
+				if err := protoOneofToOneof(filter, od, src, dst, userOptions); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		subFilter, ok := filter.Filter(goName(dstFd))
+		if !ok {
+			continue
+		}
+		if err := protoFieldToField(subFilter, srcFields.ByNumber(dstFd.Number()), dstFd, src, dst, userOptions); err != nil {
+			return err
+		}
+	}
+	if !filter.IsEmpty() {
+		return nil
+	}
+
+	// Replace the extensions and the unknown fields.
+	extensions := make(map[protoreflect.FieldNumber]protoreflect.FieldDescriptor)
+	collectExtensions := func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.IsExtension() {
+			extensions[fd.Number()] = fd
+		}
+		return true
+	}
+	dst.Range(collectExtensions)
+	src.Range(collectExtensions)
+	for _, fd := range extensions {
+		if err := protoFieldToField(filter, fd, fd, src, dst, userOptions); err != nil {
+			return err
+		}
+	}
+	dst.SetUnknown(append(protoreflect.RawFields(nil), src.GetUnknown()...))
+	return nil
+}
+
+// protoFieldToField copies the `srcFd` field of the `src` message to the `dstFd` field of the `dst` message.
+// `srcFd` is nil if the `src` message does not have such a field, in which case the field is cleared in `dst`.
+func protoFieldToField(filter FieldFilter, srcFd, dstFd protoreflect.FieldDescriptor, src, dst protoreflect.Message,
+	userOptions *options) error {
+	if srcFd != nil && (srcFd.Kind() != dstFd.Kind() || srcFd.IsList() != dstFd.IsList() || srcFd.IsMap() != dstFd.IsMap()) {
+		return errors.Errorf("src field %s is incompatible with dst field %s", srcFd.FullName(), dstFd.FullName())
+	}
+	switch {
+	case dstFd.IsList():
+		return protoListToList(filter, srcFd, dstFd, src, dst, userOptions)
+	case dstFd.IsMap():
+		return protoMapToMap(filter, srcFd, dstFd, src, dst, userOptions)
+	}
+	if srcFd == nil || !src.Has(srcFd) {
+		dst.Clear(dstFd)
+		return nil
+	}
+	if dstFd.Message() != nil {
+		return protoMessageToMessage(filter, src.Get(srcFd).Message(), dst.Mutable(dstFd).Message(), userOptions)
+	}
+	dst.Set(dstFd, protoScalar(src.Get(srcFd)))
+	return nil
+}
+
+// protoListToList copies the items of the repeated `srcFd` field to the items of the repeated `dstFd` field.
+// The message items existing in `dst` are updated in place.
+func protoListToList(filter FieldFilter, srcFd, dstFd protoreflect.FieldDescriptor, src, dst protoreflect.Message,
+	userOptions *options) error {
+	itemsFilter, ok := itemFilter(filter)
+	if !ok {
+		return nil
+	}
+	if srcFd == nil || !src.Has(srcFd) {
+		dst.Clear(dstFd)
+		return nil
+	}
+	srcList, dstList := src.Get(srcFd).List(), dst.Mutable(dstFd).List()
+	for i := 0; i < srcList.Len(); i++ {
+		if dstFd.Message() == nil {
+			if i < dstList.Len() {
+				dstList.Set(i, protoScalar(srcList.Get(i)))
+			} else {
+				dstList.Append(protoScalar(srcList.Get(i)))
+			}
+			continue
+		}
+		var item protoreflect.Value
+		if i < dstList.Len() {
+			item = dstList.Get(i)
+		} else {
+			item = dstList.NewElement()
+		}
+		if err := protoMessageToMessage(itemsFilter, srcList.Get(i).Message(), item.Message(), userOptions); err != nil {
+			return err
+		}
+		if i == dstList.Len() {
+			dstList.Append(item)
+		}
+	}
+	if dstList.Len() > srcList.Len() {
+		dstList.Truncate(srcList.Len())
+	}
+	return nil
+}
+
+// protoMapToMap copies the entries of the `srcFd` map field to the `dstFd` map field the same way mapToMap does.
+func protoMapToMap(filter FieldFilter, srcFd, dstFd protoreflect.FieldDescriptor, src, dst protoreflect.Message,
+	userOptions *options) error {
+	if filter.IsEmpty() {
+		dst.Clear(dstFd)
+		if srcFd == nil || !src.Has(srcFd) {
+			return nil
+		}
+	}
+	var srcMap protoreflect.Map
+	if srcFd != nil {
+		srcMap = src.Get(srcFd).Map()
+	}
+	hasKey := func(k protoreflect.MapKey) bool {
+		return srcMap != nil && srcMap.Has(k)
+	}
+	dstMap := dst.Mutable(dstFd).Map()
+	var deleted []protoreflect.MapKey
+	dstMap.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		if _, ok := mapKeyFilter(filter, k.String()); ok && !hasKey(k) {
+			deleted = append(deleted, k)
+		}
+		return true
+	})
+	for _, k := range deleted {
+		dstMap.Clear(k)
+	}
+	if srcMap == nil {
+		return nil
+	}
+	var err error
+	srcMap.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+		valueFilter, ok := mapKeyFilter(filter, k.String())
+		if !ok {
+			return true
+		}
+		if dstFd.MapValue().Message() == nil {
+			dstMap.Set(k, protoScalar(v))
+			return true
+		}
+		var item protoreflect.Value
+		if dstMap.Has(k) {
+			item = dstMap.Get(k)
+		} else {
+			item = dstMap.NewValue()
+		}
+		if err = protoMessageToMessage(valueFilter, v.Message(), item.Message(), userOptions); err != nil {
+			return false
+		}
+		dstMap.Set(k, item)
+		return true
+	})
+	return err
+}
+
+// protoOneofToOneof copies the oneof `od` of the `src` message to the `dst` message the same way oneofToOneof and
+// StructToStruct do: if the filter mentions the member set in `src` or `dst` directly, only the selected members are
+// copied (or cleared), otherwise the oneof is copied as a whole if the filter selects it.
+func protoOneofToOneof(filter FieldFilter, od protoreflect.OneofDescriptor, src, dst protoreflect.Message,
+	userOptions *options) error {
+	var srcMember protoreflect.FieldDescriptor
+	if srcOd := src.Descriptor().Oneofs().ByName(od.Name()); srcOd != nil {
+		srcMember = src.WhichOneof(srcOd)
+	}
+	dstMember := dst.WhichOneof(od)
+	dstFields := dst.Descriptor().Fields()
+
+	oneofName := goName(od)
+	if (srcMember != nil && mentionsField(filter, goName(srcMember))) ||
+		(dstMember != nil && mentionsField(filter, goName(dstMember))) {
+		if srcMember != nil {
+			if memberFilter, ok := oneofMemberFilter(filter, oneofName, goName(srcMember)); ok {
+				return protoFieldToField(memberFilter, srcMember, dstFields.ByNumber(srcMember.Number()), src, dst,
+					userOptions)
+			}
+		}
+		if dstMember != nil && (srcMember == nil || dstMember.Number() != srcMember.Number()) {
+			if _, ok := oneofMemberFilter(filter, oneofName, goName(dstMember)); ok {
+				dst.Clear(dstMember)
+			}
+		}
+		return nil
+	}
+
+	subFilter, ok := filter.Filter(oneofName)
+	if !ok {
+		return nil
+	}
+	if userOptions.pathTracker != nil {
+		members := make([]string, od.Fields().Len())
+		for i := range members {
+			members[i] = goName(od.Fields().Get(i))
+		}
+		userOptions.pathTracker.visit(subFilter, members, nil)
+	}
+	if srcMember == nil {
+		if dstMember != nil {
+			dst.Clear(dstMember)
+		}
+		return nil
+	}
+	if !subFilter.IsEmpty() {
+		if subFilter, ok = subFilter.Filter(goName(srcMember)); !ok {
+			return nil
+		}
+	}
+	return protoFieldToField(subFilter, srcMember, dstFields.ByNumber(srcMember.Number()), src, dst, userOptions)
+}
+
+// protoAnyToAny copies the google.protobuf.Any `src` message to the `dst` one. The messages packed into them are
+// unpacked, copied using the filter and packed back into `dst` unless the filter is empty and the UnmarshalAllAny option
+// is not set.
+func protoAnyToAny(filter FieldFilter, src, dst protoreflect.Message, userOptions *options) error {
+	srcFields, dstFields := src.Descriptor().Fields(), dst.Descriptor().Fields()
+	srcTypeURL, srcValue := srcFields.ByName("type_url"), srcFields.ByName("value")
+	dstTypeURL, dstValue := dstFields.ByName("type_url"), dstFields.ByName("value")
+	if srcTypeURL == nil || srcValue == nil || dstTypeURL == nil || dstValue == nil {
+		return errors.Errorf("%s is not a valid %s message", dst.Descriptor().FullName(), anyMessageName)
+	}
+	url := src.Get(srcTypeURL).String()
+	if url == "" || (filter.IsEmpty() && !userOptions.UnmarshalAllAny) {
+		// Copy the packed message as is.
+		dst.Set(dstTypeURL, protoreflect.ValueOfString(url))
+		dst.Set(dstValue, protoScalar(src.Get(srcValue)))
+		return nil
+	}
+
+	msgType, err := protoregistry.GlobalTypes.FindMessageByURL(url)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve %s", url)
+	}
+	srcMsg := msgType.New()
+	if err := proto.Unmarshal(src.Get(srcValue).Bytes(), srcMsg.Interface()); err != nil {
+		return errors.WithStack(err)
+	}
+	dstMsg := msgType.New()
+	if dstURL := dst.Get(dstTypeURL).String(); dstURL != "" {
+		dstType, err := protoregistry.GlobalTypes.FindMessageByURL(dstURL)
+		if err != nil {
+			return errors.Wrapf(err, "failed to resolve %s", dstURL)
+		}
+		// The dst message is replaced if it is of a different type.
+		if dstType.Descriptor().FullName() == msgType.Descriptor().FullName() {
+			if err := proto.Unmarshal(dst.Get(dstValue).Bytes(), dstMsg.Interface()); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	if err := protoMessageToMessage(filter, srcMsg, dstMsg, userOptions); err != nil {
+		return err
+	}
+	value, err := proto.Marshal(dstMsg.Interface())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	dst.Set(dstTypeURL, protoreflect.ValueOfString(url))
+	dst.Set(dstValue, protoreflect.ValueOfBytes(value))
+	return nil
+}
+
+// protoScalar returns a copy of the given scalar value that does not share the underlying bytes.
+func protoScalar(v protoreflect.Value) protoreflect.Value {
+	if b, ok := v.Interface().([]byte); ok {
+		return protoreflect.ValueOfBytes(append([]byte(nil), b...))
+	}
+	return v
+}
+
+// goName returns the name of the Go struct field generated by protoc-gen-go for the given field or oneof descriptor.
+func goName(d protoreflect.Descriptor) string {
+	return goCamelCase(string(d.Name()))
+}
+
+// goCamelCase converts the given protobuf name to a Go identifier the same way protoc-gen-go does.
+func goCamelCase(s string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '.' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip over '.' in ".{{lowercase}}".
+		case c == '.':
+			b = append(b, '_')
+		case c == '_' && (i == 0 || s[i-1] == '.'):
+			// Convert the initial '_' to ensure the name starts with a capital letter.
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isLower(s[i+1]):
+			// Skip over '_' in "_{{lowercase}}".
+		case isDigit(c):
+			b = append(b, c)
+		default:
+			// The next word is a sequence of characters that must start upper case.
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(s) && isLower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+	return string(b)
+}
@@ -3,7 +3,10 @@ package fieldmask_utils_test
 import (
 	"testing"
 
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -387,3 +390,454 @@ func TestStructToMap_PartialProtoSuccess(t *testing.T) {
 	}
 	assert.Equal(t, expected, userDst)
 }
+
+func TestStructToStruct_OneofMember(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mask     fieldmask_utils.FieldFilter
+		src      *testproto.User
+		dst      *testproto.User
+		expected *testproto.User
+	}{
+		{
+			name:     "same case selected",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Id: 1},
+			expected: &testproto.User{Id: 1, Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+		{
+			name:     "dst case replaced",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			expected: &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+		{
+			name:     "different src case clears dst",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			expected: &testproto.User{},
+		},
+		{
+			name:     "unset src case clears dst",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{},
+			dst:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			expected: &testproto.User{},
+		},
+		{
+			name:     "unselected case left intact",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Maggy"}},
+			expected: &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Maggy"}},
+		},
+		{
+			name:     "unselected src case is not copied",
+			mask:     fieldmask_utils.MaskFromString("MaleName"),
+			src:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:      &testproto.User{},
+			expected: &testproto.User{},
+		},
+		{
+			name:     "inverse mask excludes member",
+			mask:     fieldmask_utils.MaskInverseFromString("MaleName"),
+			src:      &testproto.User{Id: 1, Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "Bob"}},
+			expected: &testproto.User{Id: 1, Name: &testproto.User_MaleName{MaleName: "Bob"}},
+		},
+		{
+			name:     "inverse mask clears other members unset in src",
+			mask:     fieldmask_utils.MaskInverseFromString("MaleName"),
+			src:      &testproto.User{Id: 1, Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			expected: &testproto.User{Id: 1},
+		},
+		{
+			name:     "inverse mask copies other members",
+			mask:     fieldmask_utils.MaskInverseFromString("MaleName"),
+			src:      &testproto.User{Id: 1, Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			expected: &testproto.User{Id: 1, Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := fieldmask_utils.StructToStruct(testCase.mask, testCase.src, testCase.dst)
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(testCase.expected, testCase.dst), "expected %v, got %v", testCase.expected, testCase.dst)
+		})
+	}
+}
+
+func TestStructToStruct_OneofMemberDoesNotAliasSrc(t *testing.T) {
+	src := &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}}
+	dst := &testproto.User{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("MaleName"), src, dst)
+	require.NoError(t, err)
+	src.Name.(*testproto.User_MaleName).MaleName = "Bob"
+	assert.Equal(t, "John", dst.GetMaleName())
+}
+
+func TestStructToStruct_OneofMemberRoundTrip(t *testing.T) {
+	fieldMask := &field_mask.FieldMask{Paths: []string{"id", "male_name", "friends.female_name"}}
+	mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, fieldMask)
+	require.NoError(t, err)
+	userDst := &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}}
+	err = fieldmask_utils.StructToStruct(mask, testUserFull, userDst)
+	require.NoError(t, err)
+	assert.Equal(t, testUserFull.Id, userDst.Id)
+	assert.Equal(t, "John", userDst.GetMaleName())
+	require.Len(t, userDst.Friends, 1)
+	assert.Equal(t, "Maggy", userDst.Friends[0].GetFemaleName())
+	assert.Equal(t, "", userDst.Friends[0].Username)
+}
+
+func TestStructToMap_OneofMember(t *testing.T) {
+	userDst := make(map[string]interface{})
+	mask := fieldmask_utils.MaskFromString("Id,MaleName,FemaleName")
+	err := fieldmask_utils.StructToMap(mask, testUserFull, userDst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Id":         testUserFull.Id,
+		"MaleName":   "John",
+		"FemaleName": nil,
+	}, userDst)
+}
+
+func TestStructToMap_OneofMemberInverse(t *testing.T) {
+	userDst := make(map[string]interface{})
+	mask := fieldmask_utils.MaskInverseFromString("MaleName")
+	err := fieldmask_utils.StructToMap(mask, &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}}, userDst)
+	require.NoError(t, err)
+	assert.NotContains(t, userDst, "MaleName")
+	assert.NotContains(t, userDst, "Name")
+	assert.Contains(t, userDst, "FemaleName")
+	assert.Nil(t, userDst["FemaleName"])
+}
+
+func TestStructToStruct_ProtoMapKeys(t *testing.T) {
+	src := &testproto.User{Meta: map[string]string{"color": "red", "size": "XL"}}
+	dst := &testproto.User{Meta: map[string]string{"color": "blue", "weight": "heavy"}}
+	mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, &field_mask.FieldMask{
+		Paths: []string{"meta.color", "meta.weight"},
+	})
+	require.NoError(t, err)
+	err = fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"color": "red"}, dst.Meta)
+}
+
+func TestStructToMap_ProtoMapKeys(t *testing.T) {
+	userDst := make(map[string]interface{})
+	mask := fieldmask_utils.MaskFromString("Meta{foo,bar}")
+	err := fieldmask_utils.StructToMap(mask, testUserFull, userDst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Meta": map[string]string{"foo": "bar"},
+	}, userDst)
+}
+
+func TestStructToStruct_ProtoMapWildcard(t *testing.T) {
+	src := &testproto.User{ImagesBySize: map[string]*testproto.Image{
+		"small": {OriginalUrl: "small.jpg", ResizedUrl: "small_resized.jpg"},
+		"large": {OriginalUrl: "large.jpg", ResizedUrl: "large_resized.jpg"},
+	}}
+	dst := &testproto.User{ImagesBySize: map[string]*testproto.Image{
+		"small": {OriginalUrl: "old_small.jpg", ResizedUrl: "old_small_resized.jpg"},
+	}}
+	mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, &field_mask.FieldMask{
+		Paths: []string{"images_by_size.*.original_url"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("ImagesBySize{*{OriginalUrl}}"), mask)
+	err = fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, "small.jpg", dst.ImagesBySize["small"].OriginalUrl)
+	assert.Equal(t, "old_small_resized.jpg", dst.ImagesBySize["small"].ResizedUrl)
+	assert.Equal(t, "large.jpg", dst.ImagesBySize["large"].OriginalUrl)
+	assert.Equal(t, "", dst.ImagesBySize["large"].ResizedUrl)
+	assert.NotSame(t, src.ImagesBySize["large"], dst.ImagesBySize["large"])
+}
+
+func TestStructToMap_ProtoMapWildcard(t *testing.T) {
+	src := &testproto.User{ImagesBySize: map[string]*testproto.Image{
+		"small": {OriginalUrl: "small.jpg", ResizedUrl: "small_resized.jpg"},
+	}}
+	userDst := make(map[string]interface{})
+	err := fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("ImagesBySize{*{ResizedUrl}}"), src, userDst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ImagesBySize": map[string]map[string]interface{}{
+			"small": {"ResizedUrl": "small_resized.jpg"},
+		},
+	}, userDst)
+}
+
+func TestStructToStruct_WithStrictPaths_Oneof(t *testing.T) {
+	src := &testproto.User{
+		Name:    &testproto.User_MaleName{MaleName: "male name"},
+		Friends: []*testproto.User{{Name: &testproto.User_FemaleName{FemaleName: "female name"}}},
+	}
+	mask := fieldmask_utils.MaskFromString("FemaleName,Friends{Name{MaleName}},ExtraUser{Anything}")
+	err := fieldmask_utils.StructToStruct(mask, src, &testproto.User{}, fieldmask_utils.WithStrictPaths())
+	assert.NoError(t, err)
+
+	mask = fieldmask_utils.MaskFromString("Nickname,Friends{Name{Nickname}}")
+	err = fieldmask_utils.StructToStruct(mask, src, &testproto.User{}, fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Friends.Name.Nickname, Nickname")
+}
+
+func TestProtoToProto(t *testing.T) {
+	alias := "johnny"
+	src := proto.Clone(testUserFull).(*testproto.User)
+	src.Alias = &alias
+	src.ImagesBySize = map[string]*testproto.Image{
+		"small": {OriginalUrl: "small.jpg", ResizedUrl: "small_resized.jpg"},
+		"large": {OriginalUrl: "large.jpg", ResizedUrl: "large_resized.jpg"},
+	}
+	dstAlias := "jane"
+	dst := &testproto.User{
+		Id:       5,
+		Username: "existing",
+		Alias:    &dstAlias,
+		Avatar:   &testproto.Image{OriginalUrl: "existing.jpg", ResizedUrl: "existing_resized.jpg"},
+		Images: []*testproto.Image{
+			{OriginalUrl: "existing1.jpg", ResizedUrl: "existing1_resized.jpg"},
+			{OriginalUrl: "existing2.jpg"},
+			{OriginalUrl: "existing3.jpg"},
+		},
+		ImagesBySize: map[string]*testproto.Image{
+			"small":   {OriginalUrl: "existing_small.jpg"},
+			"missing": {OriginalUrl: "existing_missing.jpg"},
+			"other":   {OriginalUrl: "existing_other.jpg"},
+		},
+	}
+	mask := fieldmask_utils.MaskFromString(
+		"Id,Avatar{OriginalUrl},Tags,Images{ResizedUrl},Name{MaleName},ImagesBySize{small{ResizedUrl},missing},Alias")
+	err := fieldmask_utils.ProtoToProto(mask, src, dst)
+	require.NoError(t, err)
+
+	expected := &testproto.User{
+		Id:       1,
+		Username: "existing",
+		Alias:    &alias,
+		Name:     &testproto.User_MaleName{MaleName: "John"},
+		Tags:     []string{"tag1", "tag2", "tag3"},
+		Avatar:   &testproto.Image{OriginalUrl: "original.jpg", ResizedUrl: "existing_resized.jpg"},
+		Images: []*testproto.Image{
+			{OriginalUrl: "existing1.jpg", ResizedUrl: "resized_image1.jpg"},
+			{OriginalUrl: "existing2.jpg", ResizedUrl: "resized_image2.jpg"},
+		},
+		ImagesBySize: map[string]*testproto.Image{
+			"small": {OriginalUrl: "existing_small.jpg", ResizedUrl: "small_resized.jpg"},
+			"other": {OriginalUrl: "existing_other.jpg"},
+		},
+	}
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+}
+
+func TestProtoToProto_EmptyMask(t *testing.T) {
+	dst := &testproto.User{Id: 5, Alias: proto.String("jane"), Images: []*testproto.Image{{}, {}, {}}}
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.Mask{}, testUserFull, dst)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(testUserFull, dst), "expected %v, got %v", testUserFull, dst)
+}
+
+func TestProtoToProto_Presence(t *testing.T) {
+	dst := &testproto.User{
+		Alias:  proto.String("jane"),
+		Avatar: &testproto.Image{OriginalUrl: "existing.jpg"},
+		Tags:   []string{"tag"},
+		Meta:   map[string]string{"foo": "bar"},
+		Name:   &testproto.User_FemaleName{FemaleName: "Jane"},
+	}
+	mask := fieldmask_utils.MaskFromString("Alias,Avatar,Tags,Meta,Name")
+	err := fieldmask_utils.ProtoToProto(mask, &testproto.User{}, dst)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(&testproto.User{}, dst), "got %v", dst)
+	assert.Nil(t, dst.Alias)
+	assert.Nil(t, dst.Avatar)
+}
+
+func TestProtoToProto_DoesNotAliasSrc(t *testing.T) {
+	src := proto.Clone(testUserFull).(*testproto.User)
+	dst := &testproto.User{}
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString("Avatar,Images,Tags,Meta"), src, dst)
+	require.NoError(t, err)
+
+	src.Avatar.OriginalUrl = "changed.jpg"
+	src.Images[0].OriginalUrl = "changed.jpg"
+	src.Tags[0] = "changed"
+	src.Meta["foo"] = "changed"
+	assert.Equal(t, testUserFull.Avatar.OriginalUrl, dst.Avatar.OriginalUrl)
+	assert.Equal(t, testUserFull.Images[0].OriginalUrl, dst.Images[0].OriginalUrl)
+	assert.Equal(t, testUserFull.Tags, dst.Tags)
+	assert.Equal(t, testUserFull.Meta, dst.Meta)
+}
+
+func TestProtoToProto_OneofMember(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mask     string
+		src, dst *testproto.User
+		expected *testproto.User
+	}{
+		{
+			name:     "member set in src",
+			mask:     "MaleName",
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+			expected: &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+		{
+			name:     "member set in dst only",
+			mask:     "FemaleName",
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+			expected: &testproto.User{},
+		},
+		{
+			name:     "other member not selected",
+			mask:     "FemaleName",
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "Jim"}},
+			expected: &testproto.User{Name: &testproto.User_MaleName{MaleName: "Jim"}},
+		},
+		{
+			name:     "oneof field",
+			mask:     "Name",
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+			expected: &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+		{
+			name:     "oneof field not set in src",
+			mask:     "Name",
+			src:      &testproto.User{},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+			expected: &testproto.User{},
+		},
+		{
+			name:     "member not selected in oneof field",
+			mask:     "Name{FemaleName}",
+			src:      &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+			dst:      &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+			expected: &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString(tc.mask), tc.src, tc.dst)
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(tc.expected, tc.dst), "expected %v, got %v", tc.expected, tc.dst)
+		})
+	}
+}
+
+func TestProtoToProto_MaskInverse(t *testing.T) {
+	dst := &testproto.User{Username: "existing"}
+	mask := fieldmask_utils.MaskInverseFromString("Username,Avatar{ResizedUrl},Friends,Details,ExtraUser,Images")
+	err := fieldmask_utils.ProtoToProto(mask, testUserFull, dst)
+	require.NoError(t, err)
+
+	expected := proto.Clone(testUserFull).(*testproto.User)
+	expected.Username = "existing"
+	expected.Avatar.ResizedUrl = ""
+	expected.Friends, expected.Details, expected.ExtraUser, expected.Images = nil, nil, nil, nil
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+}
+
+func TestProtoToProto_Any(t *testing.T) {
+	dst := &testproto.User{}
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString("ExtraUser{Id,Avatar{OriginalUrl}},Details"),
+		testUserFull, dst)
+	require.NoError(t, err)
+
+	assert.Equal(t, testUserFull.ExtraUser.TypeUrl, dst.ExtraUser.TypeUrl)
+	extraUser := &testproto.User{}
+	require.NoError(t, dst.ExtraUser.UnmarshalTo(extraUser))
+	expected := &testproto.User{Id: testUserFull.Id, Avatar: &testproto.Image{OriginalUrl: testUserFull.Avatar.OriginalUrl}}
+	assert.True(t, proto.Equal(expected, extraUser), "expected %v, got %v", expected, extraUser)
+	assert.True(t, proto.Equal(testUserFull.Details[0], dst.Details[0]))
+}
+
+func TestProtoToProto_UnknownAny(t *testing.T) {
+	src := &testproto.User{
+		ExtraUser: &anypb.Any{TypeUrl: "example.com/example/UnknownType", Value: []byte("unknown")},
+	}
+	dst := &testproto.User{}
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString("ExtraUser"), src, dst,
+		fieldmask_utils.WithUnmarshalAllAny(false))
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(src, dst))
+
+	err = fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString("ExtraUser{Id}"), src, dst)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestProtoToProto_UnknownFields(t *testing.T) {
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 100, protowire.VarintType), 42)
+	src := &testproto.User{Id: 1, Avatar: &testproto.Image{OriginalUrl: "original.jpg"}}
+	src.ProtoReflect().SetUnknown(unknown)
+	src.Avatar.ProtoReflect().SetUnknown(unknown)
+
+	dst := &testproto.User{}
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.MaskFromString("Id,Avatar"), src, dst)
+	require.NoError(t, err)
+	assert.Empty(t, dst.ProtoReflect().GetUnknown())
+	assert.Equal(t, unknown, []byte(dst.Avatar.ProtoReflect().GetUnknown()))
+
+	dst = &testproto.User{}
+	err = fieldmask_utils.ProtoToProto(fieldmask_utils.Mask{}, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, unknown, []byte(dst.ProtoReflect().GetUnknown()))
+}
+
+func TestProtoToProto_DynamicMessage(t *testing.T) {
+	desc := testUserFull.ProtoReflect().Descriptor()
+	data, err := proto.Marshal(testUserFull)
+	require.NoError(t, err)
+	src := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(data, src))
+
+	mask := fieldmask_utils.MaskFromString(
+		"Id,Avatar{OriginalUrl},Tags,Images{ResizedUrl},Permissions,Meta,MaleName,ExtraUser{Id,Avatar{OriginalUrl}}")
+	expected := &testproto.User{}
+	require.NoError(t, fieldmask_utils.ProtoToProto(mask, testUserFull, expected))
+
+	// Dynamic to dynamic.
+	dst := dynamicpb.NewMessage(desc)
+	require.NoError(t, fieldmask_utils.ProtoToProto(mask, src, dst))
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+
+	// Dynamic to generated and back.
+	generated := &testproto.User{}
+	require.NoError(t, fieldmask_utils.ProtoToProto(mask, src, generated))
+	assert.True(t, proto.Equal(expected, generated), "expected %v, got %v", expected, generated)
+	dst = dynamicpb.NewMessage(desc)
+	require.NoError(t, fieldmask_utils.ProtoToProto(mask, generated, dst))
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+}
+
+func TestProtoToProto_WithStrictPaths(t *testing.T) {
+	mask := fieldmask_utils.MaskFromString("FemaleName,Friends{Name{MaleName}},Avatar{OriginalUrl}")
+	err := fieldmask_utils.ProtoToProto(mask, testUserFull, &testproto.User{}, fieldmask_utils.WithStrictPaths())
+	assert.NoError(t, err)
+
+	mask = fieldmask_utils.MaskFromString("Nick,Friends{Name{Nick}},Avatar{Url}")
+	err = fieldmask_utils.ProtoToProto(mask, testUserFull, &testproto.User{}, fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Avatar.Url, Friends.Name.Nick, Nick")
+
+	// The scalar fields have no fields of their own.
+	mask = fieldmask_utils.MaskFromString("Username{X},Tags{*{X}},Meta{color{X}},FemaleName{X}")
+	err = fieldmask_utils.ProtoToProto(mask, testUserFull, &testproto.User{}, fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: FemaleName.X, Meta.color.X, Tags.*.X, Username.X")
+}
+
+func TestProtoToProto_DifferentTypes(t *testing.T) {
+	err := fieldmask_utils.ProtoToProto(fieldmask_utils.Mask{}, testUserFull, &testproto.Image{})
+	assert.Error(t, err)
+}
@@ -2365,3 +2365,420 @@ func TestStructToStruct_WithConverterHook(t *testing.T) {
 		Field4: []float64{3.141, -273.15},
 	}, dst)
 }
+
+func TestStructToStruct_MapKeys(t *testing.T) {
+	type A struct {
+		Field1 map[string]int
+		Field2 int
+	}
+	src := &A{
+		Field1: map[string]int{"a": 1, "b": 2, "c": 3},
+		Field2: 42,
+	}
+	dst := &A{
+		Field1: map[string]int{"a": 10, "d": 40, "e": 50},
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{a,b,d}")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{
+		// "a" is overwritten, "b" is inserted, "d" is deleted as it is missing in src, "e" is left intact.
+		Field1: map[string]int{"a": 1, "b": 2, "e": 50},
+	}, dst)
+}
+
+func TestStructToStruct_MapKeys_NilDst(t *testing.T) {
+	type A struct {
+		Field1 map[int]string
+	}
+	src := &A{
+		Field1: map[int]string{1: "a", 2: "b"},
+	}
+	dst := new(A)
+	mask := fieldmask_utils.MaskFromString("Field1{2,3}")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{Field1: map[int]string{2: "b"}}, dst)
+}
+
+func TestStructToStruct_MapKeys_NilSrc(t *testing.T) {
+	type A struct {
+		Field1 map[string]int
+	}
+	src := new(A)
+	dst := &A{
+		Field1: map[string]int{"a": 1, "b": 2},
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{a}")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{Field1: map[string]int{"b": 2}}, dst)
+}
+
+func TestStructToStruct_MapKeys_NestedMask(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 map[string]*B
+	}
+	src := &A{
+		Field1: map[string]*B{
+			"a": {Field1: "src a", Field2: 1},
+			"b": {Field1: "src b", Field2: 2},
+		},
+	}
+	dst := &A{
+		Field1: map[string]*B{
+			"a": {Field1: "dst a", Field2: 10},
+		},
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{a{Field1},b{Field2}}")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{
+		Field1: map[string]*B{
+			"a": {Field1: "src a", Field2: 10},
+			"b": {Field1: "", Field2: 2},
+		},
+	}, dst)
+}
+
+func TestStructToStruct_MapKeys_MaskInverse(t *testing.T) {
+	type A struct {
+		Field1 map[string]int
+	}
+	src := &A{
+		Field1: map[string]int{"a": 1, "b": 2},
+	}
+	dst := &A{
+		Field1: map[string]int{"a": 10, "c": 30},
+	}
+	mask := fieldmask_utils.MaskInverse{"Field1": fieldmask_utils.MaskInverse{"a": nil}}
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{Field1: map[string]int{"a": 10, "b": 2}}, dst)
+}
+
+func TestStructToMap_MapKeys(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 map[string]int
+		Field2 map[string]*B
+	}
+	src := &A{
+		Field1: map[string]int{"a": 1, "b": 2, "c": 3},
+		Field2: map[string]*B{
+			"a": {Field1: "src a", Field2: 1},
+			"b": {Field1: "src b", Field2: 2},
+		},
+	}
+	existing := map[string]int{"a": 10, "d": 40, "e": 50}
+	dst := map[string]interface{}{
+		"Field1": existing,
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{a,b,d},Field2{a{Field1}}")
+	err := fieldmask_utils.StructToMap(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Field1": map[string]int{"a": 1, "b": 2, "e": 50},
+		"Field2": map[string]map[string]interface{}{
+			"a": {"Field1": "src a"},
+		},
+	}, dst)
+	// The existing dst map is not modified in place.
+	assert.Equal(t, map[string]int{"a": 10, "d": 40, "e": 50}, existing)
+}
+
+func TestStructToStruct_MapWildcard(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 map[string]*B
+	}
+	src := &A{
+		Field1: map[string]*B{
+			"a": {Field1: "src a", Field2: 1},
+			"b": {Field1: "src b", Field2: 2},
+		},
+	}
+	dst := &A{
+		Field1: map[string]*B{
+			"a": {Field1: "dst a", Field2: 10},
+			"c": {Field1: "dst c", Field2: 30},
+		},
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{*{Field1},b{Field2}}")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{
+		Field1: map[string]*B{
+			"a": {Field1: "src a", Field2: 10},
+			"b": {Field1: "", Field2: 2},
+		},
+	}, dst)
+}
+
+func TestStructToStruct_MapWildcard_MaskInverse(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 map[string]B
+	}
+	src := &A{
+		Field1: map[string]B{
+			"a": {Field1: "src a", Field2: 1},
+		},
+	}
+	dst := new(A)
+	mask := fieldmask_utils.MaskInverse{"Field1": fieldmask_utils.MaskInverse{"*": fieldmask_utils.MaskInverse{"Field2": nil}}}
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{Field1: map[string]B{"a": {Field1: "src a"}}}, dst)
+}
+
+func TestStructToStruct_EntireMap_DeepCopy(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 []int
+	}
+	type A struct {
+		Field1 map[string]*B
+		Field2 map[string][]int
+	}
+	src := &A{
+		Field1: map[string]*B{"a": {Field1: "src a", Field2: []int{1, 2}}},
+		Field2: map[string][]int{"a": {1, 2}},
+	}
+	dst := &A{
+		Field1: map[string]*B{"b": {Field1: "dst b"}},
+	}
+	mask := fieldmask_utils.MaskFromString("Field1,Field2")
+	err := fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, src, dst)
+
+	// Mutating src does not affect dst.
+	src.Field1["a"].Field1 = "changed"
+	src.Field1["c"] = &B{}
+	src.Field2["a"][0] = 42
+	assert.Equal(t, &A{
+		Field1: map[string]*B{"a": {Field1: "src a", Field2: []int{1, 2}}},
+		Field2: map[string][]int{"a": {1, 2}},
+	}, dst)
+}
+
+func TestStructToStruct_StructWildcard(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 B
+		Field2 B
+		Field3 int
+	}
+	src := &A{
+		Field1: B{Field1: "src 1", Field2: 1},
+		Field2: B{Field1: "src 2", Field2: 2},
+		Field3: 3,
+	}
+	dst := &A{
+		Field1: B{Field1: "dst 1", Field2: 10},
+		Field2: B{Field1: "dst 2", Field2: 20},
+		Field3: 30,
+	}
+	mask, err := fieldmask_utils.MaskFromPaths([]string{"*", "Field1.Field1"}, func(s string) string { return s })
+	require.NoError(t, err)
+	err = fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{
+		Field1: B{Field1: "src 1", Field2: 10},
+		Field2: B{Field1: "src 2", Field2: 2},
+		Field3: 3,
+	}, dst)
+}
+
+func TestStructToStruct_ListWildcard(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 []B
+		Field2 [2]B
+	}
+	src := &A{
+		Field1: []B{{Field1: "src 1", Field2: 1}, {Field1: "src 2", Field2: 2}},
+		Field2: [2]B{{Field1: "src 3", Field2: 3}, {Field1: "src 4", Field2: 4}},
+	}
+	dst := &A{
+		Field1: []B{{Field1: "dst 1", Field2: 10}},
+		Field2: [2]B{{Field1: "dst 3", Field2: 30}},
+	}
+	mask, err := fieldmask_utils.MaskFromPaths([]string{"Field1.*.Field1", "Field2.*.Field2"},
+		func(s string) string { return s })
+	require.NoError(t, err)
+	err = fieldmask_utils.StructToStruct(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, &A{
+		Field1: []B{{Field1: "src 1", Field2: 10}, {Field1: "src 2"}},
+		Field2: [2]B{{Field1: "dst 3", Field2: 3}, {Field2: 4}},
+	}, dst)
+}
+
+func TestStructToMap_ListWildcard(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 []B
+	}
+	src := &A{
+		Field1: []B{{Field1: "src 1", Field2: 1}, {Field1: "src 2", Field2: 2}},
+	}
+	dst := make(map[string]interface{})
+	mask, err := fieldmask_utils.MaskFromPaths([]string{"Field1.*.Field2"}, func(s string) string { return s })
+	require.NoError(t, err)
+	err = fieldmask_utils.StructToMap(mask, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"Field1": []map[string]interface{}{
+			{"Field2": 1},
+			{"Field2": 2},
+		},
+	}, dst)
+}
+
+func TestStructToStruct_WithStrictPaths(t *testing.T) {
+	type B struct {
+		Field1 string
+		Field2 int
+	}
+	type A struct {
+		Field1 string
+		Field2 *B
+		Field3 []B
+		Field4 map[string]B
+	}
+	src := &A{
+		Field1: "src",
+		Field2: &B{Field1: "src b"},
+		Field3: []B{{Field1: "src item 1"}, {Field1: "src item 2"}},
+		Field4: map[string]B{"a": {Field1: "src a"}},
+	}
+	testCases := []struct {
+		mask          string
+		expectedError string
+	}{
+		{"Field1,Field2{Field1},Field3{Field2},Field4{a{Field1},b}", ""},
+		{"Field3{*{Field1}},*", ""},
+		{"Feld1,Field2{Field3},Field3{Field3,Field1{Foo}}", "unknown paths: Feld1, Field2.Field3, Field3.Field1.Foo, Field3.Field3"},
+		{"Field4{a{Foo}}", "unknown paths: Field4.a.Foo"},
+	}
+	for _, testCase := range testCases {
+		dst := &A{}
+		err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString(testCase.mask), src, dst,
+			fieldmask_utils.WithStrictPaths())
+		if testCase.expectedError == "" {
+			assert.NoError(t, err, testCase.mask)
+		} else {
+			assert.EqualError(t, err, testCase.expectedError, testCase.mask)
+		}
+	}
+}
+
+func TestStructToStruct_WithStrictPaths_EmptyList(t *testing.T) {
+	type B struct {
+		Field1 string
+	}
+	type A struct {
+		Field1 []B
+		Field2 *B
+	}
+	mask := fieldmask_utils.MaskFromString("Field1{Foo},Field2{Bar}")
+	err := fieldmask_utils.StructToStruct(mask, &A{}, &A{}, fieldmask_utils.WithStrictPaths())
+	// The sub-filters have never been applied to a struct.
+	assert.NoError(t, err)
+
+	err = fieldmask_utils.StructToStruct(mask, &A{Field1: []B{{}}, Field2: &B{}}, &A{}, fieldmask_utils.WithStrictPaths())
+	var unknownPathsErr *fieldmask_utils.UnknownPathsError
+	require.True(t, errors.As(err, &unknownPathsErr))
+	assert.Equal(t, []string{"Field1.Foo", "Field2.Bar"}, unknownPathsErr.Paths)
+}
+
+func TestStructToStruct_WithStrictPaths_MaskInverse(t *testing.T) {
+	type A struct {
+		Field1 string
+		Field2 string
+	}
+	dst := &A{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskInverseFromString("Field1,Field3"),
+		&A{Field1: "src 1", Field2: "src 2"}, dst, fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Field3")
+	// The matched fields are copied anyway.
+	assert.Equal(t, &A{Field2: "src 2"}, dst)
+}
+
+func TestStructToStruct_WithStrictPaths_SharedSubMask(t *testing.T) {
+	type B struct {
+		Field1 string
+	}
+	type C struct {
+		Field2 string
+	}
+	type A struct {
+		Field1 *B
+		Field2 *C
+	}
+	// The same sub-mask is applied to both fields: its entries are matched by the fields of either of them.
+	shared := fieldmask_utils.MaskFromString("Field1,Field2")
+	mask := fieldmask_utils.Mask{"Field1": shared, "Field2": shared}
+	err := fieldmask_utils.StructToStruct(mask, &A{Field1: &B{}, Field2: &C{}}, &A{}, fieldmask_utils.WithStrictPaths())
+	assert.NoError(t, err)
+}
+
+func TestStructToStruct_WithStrictPaths_ScalarLeaf(t *testing.T) {
+	type A struct {
+		Name  string
+		Tags  []string
+		Meta  map[string]int
+		Count *int
+	}
+	src := &A{Name: "src", Tags: []string{"a"}, Meta: map[string]int{"a": 1}}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.Mask{"Name": fieldmask_utils.Mask{"X": nil}}, src, &A{},
+		fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Name.X")
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Name{*},Tags{*{X}},Meta{a{X},b},Count{X}"), src,
+		&A{}, fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Count.X, Meta.a.X, Name.*, Tags.*.X")
+
+	err = fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Name{X}"), src, map[string]interface{}{},
+		fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Name.X")
+}
+
+func TestStructToMap_WithStrictPaths(t *testing.T) {
+	type B struct {
+		Field1 string `json:"field_1"`
+	}
+	type A struct {
+		Field1 string `json:"field_1"`
+		Field2 []*B   `json:"field_2"`
+	}
+	src := &A{Field1: "src", Field2: []*B{{Field1: "src item"}}}
+	dst := make(map[string]interface{})
+	err := fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("field_1,field_2{field_1,field_3},Field1"), src, dst,
+		fieldmask_utils.WithSrcTag("json"), fieldmask_utils.WithStrictPaths())
+	assert.EqualError(t, err, "unknown paths: Field1, field_2.field_3")
+}
@@ -0,0 +1,110 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// deepCopy returns a copy of the given value which does not share any memory with it: pointers, slices and maps are
+// freshly allocated and copied recursively. Protobuf messages are copied with proto.Clone, the unexported fields of
+// other structs are copied as is.
+// The pointers, slices and maps which are reachable more than once (e.g. cyclic ones) are copied once, so that the copy
+// has the same shape as the value and the cycles do not make the copy recurse forever.
+func deepCopy(v reflect.Value) reflect.Value {
+	return deepCopyValue(v, make(map[deepCopyKey]reflect.Value))
+}
+
+// deepCopyKey identifies a pointer, a slice or a map which has been copied. Like in the cycle detection of
+// encoding/json, slices are identified by their length too, as different slices may share the same underlying array.
+type deepCopyKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// deepCopyValue is the same as deepCopy which reuses the copies of the pointers, slices and maps held in the given map.
+func deepCopyValue(v reflect.Value, copies map[deepCopyKey]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		if msg, ok := v.Interface().(proto.Message); ok {
+			return reflect.ValueOf(proto.Clone(msg))
+		}
+		key := deepCopyKey{ptr: v.Pointer(), typ: v.Type()}
+		if result, ok := copies[key]; ok {
+			return result
+		}
+		result := reflect.New(v.Type().Elem())
+		copies[key] = result
+		result.Elem().Set(deepCopyValue(v.Elem(), copies))
+		return result
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		result := reflect.New(v.Type()).Elem()
+		result.Set(deepCopyValue(v.Elem(), copies))
+		return result
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := deepCopyKey{ptr: v.Pointer(), len: v.Len(), typ: v.Type()}
+		if result, ok := copies[key]; ok {
+			return result
+		}
+		result := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		copies[key] = result
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(deepCopyValue(v.Index(i), copies))
+		}
+		return result
+
+	case reflect.Array:
+		result := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			result.Index(i).Set(deepCopyValue(v.Index(i), copies))
+		}
+		return result
+
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := deepCopyKey{ptr: v.Pointer(), typ: v.Type()}
+		if result, ok := copies[key]; ok {
+			return result
+		}
+		result := reflect.MakeMapWithSize(v.Type(), v.Len())
+		copies[key] = result
+		for _, key := range v.MapKeys() {
+			result.SetMapIndex(key, deepCopyValue(v.MapIndex(key), copies))
+		}
+		return result
+
+	case reflect.Struct:
+		if reflect.PtrTo(v.Type()).Implements(protoMessageInterface) {
+			addr := v
+			if !v.CanAddr() {
+				// Messages are cloned through a pointer: copy the struct to an addressable value first.
+				addr = reflect.New(v.Type()).Elem()
+				addr.Set(v)
+			}
+			return reflect.ValueOf(proto.Clone(addr.Addr().Interface().(proto.Message))).Elem()
+		}
+		result := reflect.New(v.Type()).Elem()
+		result.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if isExported(v.Type().Field(i)) {
+				result.Field(i).Set(deepCopyValue(v.Field(i), copies))
+			}
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,187 @@
+package fieldmask_utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+type deepCopyInner struct {
+	Tags []string
+}
+
+type deepCopySrc struct {
+	Inner  deepCopyInner
+	Ptr    *deepCopyInner
+	Value  int
+	Values map[string]interface{}
+	Bytes  []byte
+}
+
+type deepCopyDst struct {
+	Inner  deepCopyInner
+	Ptr    *deepCopyInner
+	Value  *int
+	Values map[string]interface{}
+	Bytes  []byte
+}
+
+func newDeepCopySrc() *deepCopySrc {
+	return &deepCopySrc{
+		Inner:  deepCopyInner{Tags: []string{"a", "b"}},
+		Ptr:    &deepCopyInner{Tags: []string{"c"}},
+		Value:  42,
+		Values: map[string]interface{}{"inner": &deepCopyInner{Tags: []string{"d"}}},
+		Bytes:  []byte("bytes"),
+	}
+}
+
+func TestStructToStruct_AliasesSrcByDefault(t *testing.T) {
+	src := newDeepCopySrc()
+	dst := &deepCopyDst{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Inner,Value,Values"), src, dst)
+	require.NoError(t, err)
+
+	// A struct selected with an empty mask is assigned as a whole.
+	src.Inner.Tags[0] = "changed"
+	assert.Equal(t, "changed", dst.Inner.Tags[0])
+	// A pointer is set to the address of the src field.
+	assert.Same(t, &src.Value, dst.Value)
+	// The values stored in the interfaces of a map are shared.
+	assert.Same(t, src.Values["inner"], dst.Values["inner"])
+}
+
+func TestStructToStruct_WithDeepCopy(t *testing.T) {
+	src := newDeepCopySrc()
+	dst := &deepCopyDst{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Inner,Ptr,Value,Values,Bytes"), src, dst,
+		fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+
+	expected := newDeepCopySrc()
+	assert.Equal(t, expected.Inner, dst.Inner)
+	assert.Equal(t, expected.Ptr, dst.Ptr)
+	assert.Equal(t, expected.Value, *dst.Value)
+	assert.Equal(t, expected.Values, dst.Values)
+	assert.Equal(t, expected.Bytes, dst.Bytes)
+
+	src.Inner.Tags[0] = "changed"
+	src.Ptr.Tags[0] = "changed"
+	src.Value = 0
+	src.Values["inner"].(*deepCopyInner).Tags[0] = "changed"
+	src.Bytes[0] = 'B'
+	assert.Equal(t, expected.Inner, dst.Inner)
+	assert.Equal(t, expected.Ptr, dst.Ptr)
+	assert.Equal(t, expected.Value, *dst.Value)
+	assert.Equal(t, expected.Values, dst.Values)
+	assert.Equal(t, expected.Bytes, dst.Bytes)
+}
+
+func TestStructToStruct_WithDeepCopy_Proto(t *testing.T) {
+	src := proto.Clone(testUserFull).(*testproto.User)
+	dst := &testproto.User{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.Mask{}, src, dst, fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(testUserFull, dst))
+
+	src.Avatar.OriginalUrl = "changed.jpg"
+	src.Images[0].OriginalUrl = "changed.jpg"
+	src.Tags[0] = "changed"
+	src.Meta["foo"] = "changed"
+	src.ExtraUser.Value[0]++
+	assert.True(t, proto.Equal(testUserFull, dst))
+}
+
+func TestStructToStruct_WithDeepCopy_ProtoValueInInterface(t *testing.T) {
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 100, protowire.VarintType), 1)
+	img := &testproto.Image{OriginalUrl: "a.jpg"}
+	img.ProtoReflect().SetUnknown(unknown)
+	// The message struct stored in the interface is not addressable.
+	src := &struct{ Extra interface{} }{Extra: reflect.ValueOf(img).Elem().Interface()}
+	dst := &struct{ Extra interface{} }{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.Mask{}, src, dst, fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+
+	unknown[len(unknown)-1]++
+	dstImg := reflect.New(reflect.TypeOf(dst.Extra))
+	dstImg.Elem().Set(reflect.ValueOf(dst.Extra))
+	assert.Equal(t, "a.jpg", dstImg.Interface().(*testproto.Image).OriginalUrl)
+	assert.Equal(t, protoreflect.RawFields{0xa0, 0x06, 0x01},
+		dstImg.Interface().(*testproto.Image).ProtoReflect().GetUnknown())
+}
+
+func TestStructToStruct_WithDeepCopy_AnyNotUnmarshaled(t *testing.T) {
+	src := &testproto.User{ExtraUser: &anypb.Any{TypeUrl: "example.com/example/UnknownType", Value: []byte("unknown")}}
+	dst := &testproto.User{}
+	mask := fieldmask_utils.MaskFromString("ExtraUser")
+	err := fieldmask_utils.StructToStruct(mask, src, dst, fieldmask_utils.WithUnmarshalAllAny(false))
+	require.NoError(t, err)
+	assert.Same(t, src.ExtraUser, dst.ExtraUser)
+
+	dst = &testproto.User{}
+	err = fieldmask_utils.StructToStruct(mask, src, dst, fieldmask_utils.WithUnmarshalAllAny(false),
+		fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+	assert.NotSame(t, src.ExtraUser, dst.ExtraUser)
+	assert.True(t, proto.Equal(src.ExtraUser, dst.ExtraUser))
+}
+
+func TestStructToMap_AliasesSrcByDefault(t *testing.T) {
+	src := &testproto.User{Tags: []string{"a", "b"}, Meta: map[string]string{"foo": "bar"}}
+	dst := make(map[string]interface{})
+	err := fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Tags,Meta"), src, dst)
+	require.NoError(t, err)
+
+	src.Tags[0] = "changed"
+	src.Meta["foo"] = "changed"
+	assert.Equal(t, []string{"changed", "b"}, dst["Tags"])
+	assert.Equal(t, map[string]string{"foo": "changed"}, dst["Meta"])
+}
+
+func TestStructToMap_WithDeepCopy(t *testing.T) {
+	src := &testproto.User{Tags: []string{"a", "b"}, Meta: map[string]string{"foo": "bar"}}
+	dst := make(map[string]interface{})
+	err := fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Tags,Meta"), src, dst,
+		fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+
+	src.Tags[0] = "changed"
+	src.Meta["foo"] = "changed"
+	assert.Equal(t, []string{"a", "b"}, dst["Tags"])
+	assert.Equal(t, map[string]string{"foo": "bar"}, dst["Meta"])
+}
+
+type deepCopyNode struct {
+	Name     string
+	Next     *deepCopyNode
+	Children []interface{}
+	Meta     map[string]interface{}
+}
+
+func TestStructToStruct_WithDeepCopy_Cycles(t *testing.T) {
+	src := &deepCopyNode{Name: "a", Meta: map[string]interface{}{}}
+	src.Next = &deepCopyNode{Name: "b", Next: src}
+	src.Children = []interface{}{src.Next}
+	src.Meta["self"] = src.Meta
+	dst := &deepCopyNode{}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.Mask{}, src, dst, fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+
+	assert.Equal(t, "a", dst.Name)
+	assert.Equal(t, "b", dst.Next.Name)
+	assert.NotSame(t, src.Next, dst.Next)
+	// The cycles are preserved in the copy rather than followed forever.
+	assert.Same(t, dst.Next.Next.Next, dst.Next)
+	assert.Same(t, dst.Next, dst.Children[0])
+	assert.Equal(t, reflect.ValueOf(dst.Meta).Pointer(), reflect.ValueOf(dst.Meta["self"]).Pointer())
+	assert.NotEqual(t, reflect.ValueOf(src.Meta).Pointer(), reflect.ValueOf(dst.Meta).Pointer())
+}
@@ -0,0 +1,271 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// DiffMask returns the minimal Mask that covers all the fields which differ in `a` and `b`, so that
+// StructToStruct(DiffMask(a, b), a, b) makes `b` equal to `a`.
+// `a` and `b` must be structs (or pointers to structs) coherent in terms of the field names, the same way StructToStruct
+// requires. The values are traversed the same way StructToStruct does:
+//   - pointers and interfaces are followed, a nil value differs from a non-nil one as a whole;
+//   - the fields of the messages in google.protobuf.Any are compared if the messages are of the same type;
+//   - lists of different lengths differ as a whole, otherwise the masks of their items are merged together;
+//   - the keys of maps are compared individually, e.g. "Meta{color}";
+//   - members of protobuf oneofs are addressed by their own names, e.g. "MaleName";
+//   - structs without exported fields (like time.Time) are compared with reflect.DeepEqual.
+//
+// Only the fields selected by the filter set with the WithFilter option are compared (all the fields by default).
+// Field names are resolved according to the WithSrcTag option.
+// Note that the result is an empty Mask if the values do not differ, which copies all the fields when used with
+// StructToStruct: check it with IsEmpty first.
+func DiffMask(a, b interface{}, opts ...Option) (Mask, error) {
+	userOptions := newDefaultOptions()
+	for _, o := range opts {
+		o(userOptions)
+	}
+	aVal, bVal := indirect(reflect.ValueOf(a)), indirect(reflect.ValueOf(b))
+	if aVal.Kind() != reflect.Struct {
+		return nil, errors.Errorf("a kind must be a struct, %s given", aVal.Kind())
+	}
+	if bVal.Kind() != reflect.Struct {
+		return nil, errors.Errorf("b kind must be a struct, %s given", bVal.Kind())
+	}
+	filter := userOptions.Filter
+	if filter == nil {
+		filter = Mask{}
+	}
+	mask, _, err := diff(filter, aVal, bVal, userOptions)
+	return mask, err
+}
+
+// diff returns the Mask of the differences of the given values and true if they differ. An empty Mask is returned if
+// the values differ as a whole.
+func diff(filter FieldFilter, a, b reflect.Value, userOptions *options) (Mask, bool, error) {
+	if a.Kind() != b.Kind() {
+		// Either of the values can be a pointer to the type of the other one.
+		if a.Kind() == reflect.Ptr {
+			if a.IsNil() {
+				return Mask{}, true, nil
+			}
+			a = a.Elem()
+		} else if b.Kind() == reflect.Ptr {
+			if b.IsNil() {
+				return Mask{}, true, nil
+			}
+			b = b.Elem()
+		}
+		if a.Kind() != b.Kind() {
+			return nil, false, errors.Errorf("kind %s differs from kind %s", a.Kind(), b.Kind())
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		return diffStructs(filter, a, b, userOptions)
+
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return Mask{}, a.IsNil() != b.IsNil(), nil
+		}
+		if aAny, ok := a.Interface().(*anypb.Any); ok {
+			return diffAny(filter, aAny, b, userOptions)
+		}
+		return diff(filter, a.Elem(), b.Elem(), userOptions)
+
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return Mask{}, a.IsNil() != b.IsNil(), nil
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return Mask{}, true, nil
+		}
+		return diff(filter, a.Elem(), b.Elem(), userOptions)
+
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return Mask{}, true, nil
+		}
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			return nil, false, nil
+		}
+		mask := Mask{}
+		for i := 0; i < a.Len(); i++ {
+			sub, changed, err := diff(itemsFilter, a.Index(i), b.Index(i), userOptions)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if sub.IsEmpty() {
+				return Mask{}, true, nil
+			}
+			union, err := Union(mask, sub)
+			if err != nil {
+				return nil, false, err
+			}
+			mask = union.(Mask)
+		}
+		return mask, !mask.IsEmpty(), nil
+
+	case reflect.Map:
+		return diffMaps(filter, a, b, userOptions)
+
+	default:
+		return Mask{}, !reflect.DeepEqual(a.Interface(), b.Interface()), nil
+	}
+}
+
+func diffStructs(filter FieldFilter, a, b reflect.Value, userOptions *options) (Mask, bool, error) {
+	aType := a.Type()
+	if !hasExportedFields(aType) {
+		return Mask{}, !reflect.DeepEqual(a.Interface(), b.Interface()), nil
+	}
+	mask := Mask{}
+	for i := 0; i < a.NumField(); i++ {
+		field := aType.Field(i)
+		if !isExported(field) {
+			continue
+		}
+		name := fieldName(userOptions.SrcTag, field)
+		bField := b.FieldByName(field.Name)
+		if !bField.IsValid() {
+			return nil, false, errors.Errorf("field %s is not found in %s", field.Name, b.Type())
+		}
+		if isOneof(field) {
+			if err := diffOneofs(filter, name, a.Field(i), bField, mask, userOptions); err != nil {
+				return nil, false, err
+			}
+			continue
+		}
+		subFilter, ok := filter.Filter(name)
+		if !ok {
+			continue
+		}
+		sub, changed, err := diff(subFilter, a.Field(i), bField, userOptions)
+		if err != nil {
+			return nil, false, err
+		}
+		if changed {
+			mask[name] = sub
+		}
+	}
+	return mask, !mask.IsEmpty(), nil
+}
+
+// diffOneofs adds the differences of the given oneof values to the mask. The members are added by their own names.
+func diffOneofs(filter FieldFilter, oneofName string, a, b reflect.Value, mask Mask, userOptions *options) error {
+	aCase, aOk := oneofCase(a)
+	bCase, bOk := oneofCase(b)
+	if aOk && bOk && aCase.Name == bCase.Name {
+		memberName := fieldName(userOptions.SrcTag, aCase)
+		subFilter, ok := oneofMemberFilter(filter, oneofName, memberName)
+		if !ok {
+			return nil
+		}
+		sub, changed, err := diff(subFilter, a.Elem().Elem().Field(0), b.Elem().Elem().Field(0), userOptions)
+		if err != nil {
+			return err
+		}
+		if changed {
+			mask[memberName] = sub
+		}
+		return nil
+	}
+	// Different members are set: both of them differ as a whole.
+	for _, member := range []struct {
+		field reflect.StructField
+		ok    bool
+	}{{aCase, aOk}, {bCase, bOk}} {
+		if !member.ok {
+			continue
+		}
+		memberName := fieldName(userOptions.SrcTag, member.field)
+		if _, ok := oneofMemberFilter(filter, oneofName, memberName); ok {
+			mask[memberName] = Mask{}
+		}
+	}
+	return nil
+}
+
+func diffAny(filter FieldFilter, a *anypb.Any, b reflect.Value, userOptions *options) (Mask, bool, error) {
+	bAny, ok := b.Interface().(*anypb.Any)
+	if !ok {
+		return nil, false, errors.Errorf("b type is %s, expected: %s", b.Type(), "*any.Any")
+	}
+	if proto.Equal(a, bAny) {
+		return Mask{}, false, nil
+	}
+	if a.GetTypeUrl() != bAny.GetTypeUrl() {
+		return Mask{}, true, nil
+	}
+	aProto, err := a.UnmarshalNew()
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	bProto, err := bAny.UnmarshalNew()
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	return diff(filter, reflect.ValueOf(aProto), reflect.ValueOf(bProto), userOptions)
+}
+
+func diffMaps(filter FieldFilter, a, b reflect.Value, userOptions *options) (Mask, bool, error) {
+	if a.Len() == 0 && b.Len() == 0 {
+		return Mask{}, false, nil
+	}
+	mask := Mask{}
+	for _, key := range a.MapKeys() {
+		keyName := mapKeyString(key)
+		subFilter, ok := mapKeyFilter(filter, keyName)
+		if !ok {
+			continue
+		}
+		bKey, err := convertMapKey(key, b.Type().Key())
+		if err != nil {
+			return nil, false, err
+		}
+		bValue := b.MapIndex(bKey)
+		if !bValue.IsValid() {
+			mask[keyName] = Mask{}
+			continue
+		}
+		sub, changed, err := diff(subFilter, a.MapIndex(key), bValue, userOptions)
+		if err != nil {
+			return nil, false, err
+		}
+		if changed {
+			mask[keyName] = sub
+		}
+	}
+	for _, key := range b.MapKeys() {
+		keyName := mapKeyString(key)
+		if _, ok := mapKeyFilter(filter, keyName); !ok {
+			continue
+		}
+		aKey, err := convertMapKey(key, a.Type().Key())
+		if err != nil {
+			return nil, false, err
+		}
+		if !a.MapIndex(aKey).IsValid() {
+			mask[keyName] = Mask{}
+		}
+	}
+	return mask, !mask.IsEmpty(), nil
+}
+
+// hasExportedFields returns true if the given struct type has at least one exported field.
+func hasExportedFields(structType reflect.Type) bool {
+	for i := 0; i < structType.NumField(); i++ {
+		if isExported(structType.Field(i)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package fieldmask_utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+type diffB struct {
+	Field1 string `json:"field_1"`
+	Field2 int    `json:"field_2"`
+}
+
+type diffA struct {
+	Field1 string            `json:"field_1"`
+	Field2 *diffB            `json:"field_2"`
+	Field3 []diffB           `json:"field_3"`
+	Field4 [2]int            `json:"field_4"`
+	Field5 map[string]*diffB `json:"field_5"`
+	Field6 interface{}       `json:"field_6"`
+	Field7 time.Time         `json:"field_7"`
+	Field8 []string          `json:"field_8"`
+}
+
+func TestDiffMask(t *testing.T) {
+	now := time.Now()
+	base := func() *diffA {
+		return &diffA{
+			Field1: "a",
+			Field2: &diffB{Field1: "b", Field2: 1},
+			Field3: []diffB{{Field1: "c", Field2: 2}, {Field1: "d", Field2: 3}},
+			Field4: [2]int{1, 2},
+			Field5: map[string]*diffB{"x": {Field1: "x"}, "y": {Field1: "y"}},
+			Field6: &diffB{Field1: "e"},
+			Field7: now,
+			Field8: []string{"f"},
+		}
+	}
+	testCases := []struct {
+		name         string
+		change       func(a *diffA)
+		expectedMask string
+	}{
+		{"no changes", func(a *diffA) {}, ""},
+		{"scalar", func(a *diffA) { a.Field1 = "changed" }, "Field1"},
+		{"pointer field", func(a *diffA) { a.Field2.Field2 = 10 }, "Field2{Field2}"},
+		{"nil pointer", func(a *diffA) { a.Field2 = nil }, "Field2"},
+		{"list items", func(a *diffA) {
+			a.Field3[0].Field1 = "changed"
+			a.Field3[1].Field2 = 20
+		}, "Field3{Field1,Field2}"},
+		{"list length", func(a *diffA) { a.Field3 = a.Field3[:1] }, "Field3"},
+		{"array", func(a *diffA) { a.Field4[1] = 20 }, "Field4"},
+		{"map value", func(a *diffA) { a.Field5["x"].Field1 = "changed" }, "Field5{x{Field1}}"},
+		{"map keys", func(a *diffA) {
+			delete(a.Field5, "x")
+			a.Field5["z"] = &diffB{}
+		}, "Field5{x,z}"},
+		{"interface", func(a *diffA) { a.Field6.(*diffB).Field2 = 5 }, "Field6{Field2}"},
+		{"interface type", func(a *diffA) { a.Field6 = "e" }, "Field6"},
+		{"struct without exported fields", func(a *diffA) { a.Field7 = now.Add(time.Second) }, "Field7"},
+		{"scalar list", func(a *diffA) { a.Field8[0] = "changed" }, "Field8"},
+	}
+	for _, testCase := range testCases {
+		b := base()
+		testCase.change(b)
+		mask, err := fieldmask_utils.DiffMask(base(), b)
+		require.NoError(t, err, testCase.name)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expectedMask), mask, testCase.name)
+	}
+}
+
+func TestDiffMask_CopyMakesEqual(t *testing.T) {
+	a := &diffA{
+		Field1: "a",
+		Field2: &diffB{Field1: "b", Field2: 1},
+		Field3: []diffB{{Field1: "c", Field2: 2}},
+		Field5: map[string]*diffB{"x": {Field1: "x"}},
+	}
+	b := &diffA{
+		Field1: "a",
+		Field2: &diffB{Field1: "b", Field2: 2},
+		Field3: []diffB{{Field1: "changed", Field2: 2}},
+		Field5: map[string]*diffB{"x": {Field1: "x", Field2: 1}, "y": {}},
+	}
+	mask, err := fieldmask_utils.DiffMask(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, "Field2{Field2},Field3{Field1},Field5{x{Field2},y}", mask.String())
+	require.NoError(t, fieldmask_utils.StructToStruct(mask, a, b))
+	assert.Equal(t, a, b)
+}
+
+func TestDiffMask_NoChanges(t *testing.T) {
+	a := &diffA{Field1: "a", Field5: map[string]*diffB{"x": {}}}
+	b := &diffA{Field1: "a", Field5: map[string]*diffB{"x": {}}}
+	mask, err := fieldmask_utils.DiffMask(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{}, mask)
+
+	// The fields that differ are not selected by the filter.
+	b.Field1 = "b"
+	mask, err = fieldmask_utils.DiffMask(a, b,
+		fieldmask_utils.WithFilter(fieldmask_utils.MaskFromString("Field5")))
+	require.NoError(t, err)
+	assert.True(t, mask.IsEmpty())
+}
+
+func TestDiffMask_WithFilter(t *testing.T) {
+	a := &diffA{Field1: "a", Field2: &diffB{Field1: "b", Field2: 1}, Field5: map[string]*diffB{"x": {}}}
+	b := &diffA{Field1: "changed", Field2: &diffB{Field1: "changed", Field2: 2}, Field5: map[string]*diffB{"y": {}}}
+
+	mask, err := fieldmask_utils.DiffMask(a, b,
+		fieldmask_utils.WithFilter(fieldmask_utils.MaskFromString("Field2{Field2},Field5{y}")))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("Field2{Field2},Field5{y}"), mask)
+
+	mask, err = fieldmask_utils.DiffMask(a, b,
+		fieldmask_utils.WithFilter(fieldmask_utils.MaskInverseFromString("Field1,Field2{Field1}")))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("Field2{Field2},Field5{x,y}"), mask)
+}
+
+func TestDiffMask_WithSrcTag(t *testing.T) {
+	a := &diffA{Field1: "a", Field2: &diffB{Field2: 1}}
+	b := &diffA{Field1: "b", Field2: &diffB{Field2: 2}}
+	mask, err := fieldmask_utils.DiffMask(a, b, fieldmask_utils.WithSrcTag("json"))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("field_1,field_2{field_2}"), mask)
+}
+
+func TestDiffMask_Proto(t *testing.T) {
+	extraUser, err := anypb.New(&testproto.User{Id: 1, Username: "extra"})
+	require.NoError(t, err)
+	changedExtraUser, err := anypb.New(&testproto.User{Id: 1, Username: "changed"})
+	require.NoError(t, err)
+	a := &testproto.User{
+		Id:        1,
+		Name:      &testproto.User_MaleName{MaleName: "male"},
+		ExtraUser: extraUser,
+		Friends:   []*testproto.User{{Name: &testproto.User_FemaleName{FemaleName: "female"}}},
+	}
+	b := &testproto.User{
+		Id:        1,
+		Name:      &testproto.User_FemaleName{FemaleName: "female"},
+		ExtraUser: changedExtraUser,
+		Friends:   []*testproto.User{{Name: &testproto.User_FemaleName{FemaleName: "changed"}}},
+	}
+	mask, err := fieldmask_utils.DiffMask(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("ExtraUser{Username},FemaleName,Friends{FemaleName},MaleName"), mask)
+}
+
+func TestDiffMask_Failure(t *testing.T) {
+	_, err := fieldmask_utils.DiffMask(1, &diffA{})
+	assert.EqualError(t, err, "a kind must be a struct, int given")
+	_, err = fieldmask_utils.DiffMask(&diffA{}, "b")
+	assert.EqualError(t, err, "b kind must be a struct, string given")
+	_, err = fieldmask_utils.DiffMask(&diffA{}, &diffB{})
+	assert.EqualError(t, err, "field Field3 is not found in fieldmask_utils_test.diffB")
+}
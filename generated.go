@@ -0,0 +1,44 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// The functions below are used by the code generated by protoc-gen-go-fieldmask, so that the generated CopyMasked and
+// ToMapMasked methods apply filters exactly the same way StructToStruct and StructToMap do.
+// They are exported for the generated code only and are NOT a part of the stable API of this package: they may change
+// or be removed in any release together with the generator. Use StructToStruct and StructToMap instead.
+
+// ItemFilter returns the FieldFilter for the items of a list. Result is false if the items are not supposed to be
+// copied. For use by the generated code only.
+func ItemFilter(filter FieldFilter) (FieldFilter, bool) {
+	return itemFilter(filter)
+}
+
+// MapKeyFilter returns the FieldFilter for the map entry with the given key (formatted with fmt.Sprint unless it is a
+// string). Result is false if the entry is not supposed to be copied. For use by the generated code only.
+func MapKeyFilter(filter FieldFilter, key string) (FieldFilter, bool) {
+	return mapKeyFilter(filter, key)
+}
+
+// MentionsField returns true if the given filter explicitly has an entry for the given field name.
+// For use by the generated code only.
+func MentionsField(filter FieldFilter, fieldName string) bool {
+	return mentionsField(filter, fieldName)
+}
+
+// OneofMemberFilter returns the FieldFilter for the given oneof member which is selected either directly by its name
+// or through the oneof field. For use by the generated code only.
+func OneofMemberFilter(filter FieldFilter, oneofName, memberName string) (FieldFilter, bool) {
+	return oneofMemberFilter(filter, oneofName, memberName)
+}
+
+// CopyAny copies the google.protobuf.Any `src` field to the `dst` field the way StructToStruct does: the messages packed
+// into them are unpacked, copied using the filter and packed back into a new `dst` message.
+// For use by the generated code only.
+func CopyAny(filter FieldFilter, src *anypb.Any, dst **anypb.Any) error {
+	srcVal, dstVal := reflect.ValueOf(src), reflect.ValueOf(dst).Elem()
+	return structToStruct(filter, &srcVal, &dstVal, newDefaultOptions())
+}
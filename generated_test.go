@@ -0,0 +1,111 @@
+package fieldmask_utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+// generatedTestFilters returns the filters the generated methods are checked against StructToStruct and StructToMap with.
+func generatedTestFilters() map[string]fieldmask_utils.FieldFilter {
+	mask, inverse := fieldmask_utils.MaskFromString, fieldmask_utils.MaskInverseFromString
+	return map[string]fieldmask_utils.FieldFilter{
+		"empty mask":         fieldmask_utils.Mask{},
+		"empty mask inverse": fieldmask_utils.MaskInverse{},
+		"nested fields": mask("Id,Avatar{OriginalUrl},Tags,Images,Permissions,Friends{Images{ResizedUrl}}," +
+			"Name{MaleName},ExtraUser{Id,Avatar{OriginalUrl}}"),
+		"oneof":              mask("Name"),
+		"oneof members":      mask("MaleName,FemaleName"),
+		"other oneof member": mask("FemaleName"),
+		"map keys":           mask("Meta{foo,missing},ImagesBySize{small{ResizedUrl},missing}"),
+		"map wildcard":       mask("ImagesBySize{*{OriginalUrl}}"),
+		"list wildcard":      mask("Images{*{OriginalUrl}},Friends{*{Id,Name}}"),
+		"optional and any":   mask("Alias,Details,ExtraUser"),
+		"empty nested masks": fieldmask_utils.Mask{
+			"Avatar": fieldmask_utils.Mask{}, "Meta": fieldmask_utils.Mask{}, "ImagesBySize": fieldmask_utils.Mask{},
+			"Friends": fieldmask_utils.Mask{},
+		},
+		"inverse":                 inverse("Id,Avatar{ResizedUrl},Friends,Name"),
+		"inverse oneof member":    inverse("MaleName,Images{OriginalUrl}"),
+		"inverse map keys":        inverse("Meta{foo},ImagesBySize{small}"),
+		"inverse nested any mask": inverse("ExtraUser{Friends,Images}"),
+	}
+}
+
+// generatedTestUsers returns the src user and the dst users the generated methods are checked with.
+func generatedTestUsers() (*testproto.User, map[string]*testproto.User) {
+	alias := "johnny"
+	src := proto.Clone(testUserFull).(*testproto.User)
+	src.Alias = &alias
+	src.ImagesBySize = map[string]*testproto.Image{
+		"small": {OriginalUrl: "small.jpg", ResizedUrl: "small_resized.jpg"},
+		"large": {OriginalUrl: "large.jpg", ResizedUrl: "large_resized.jpg"},
+	}
+	existingAlias := "jane"
+	return src, map[string]*testproto.User{
+		"empty dst": {},
+		"existing dst": {
+			Id:    5,
+			Alias: &existingAlias,
+			Name:  &testproto.User_MaleName{MaleName: "Jim"},
+			Meta:  map[string]string{"foo": "baz", "other": "value"},
+			Images: []*testproto.Image{
+				{OriginalUrl: "existing1.jpg", ResizedUrl: "existing1_resized.jpg"},
+				{OriginalUrl: "existing2.jpg"},
+				{OriginalUrl: "existing3.jpg"},
+			},
+			ImagesBySize: map[string]*testproto.Image{
+				"small":   {OriginalUrl: "existing_small.jpg"},
+				"missing": {OriginalUrl: "existing_missing.jpg"},
+			},
+		},
+		"other oneof member in dst": {Name: &testproto.User_FemaleName{FemaleName: "Jane"}},
+	}
+}
+
+func TestUser_CopyMasked(t *testing.T) {
+	src, dsts := generatedTestUsers()
+	for filterName, filter := range generatedTestFilters() {
+		for dstName, dst := range dsts {
+			t.Run(filterName+"/"+dstName, func(t *testing.T) {
+				expected := proto.Clone(dst).(*testproto.User)
+				expectedErr := fieldmask_utils.StructToStruct(filter, src, expected)
+				actual := proto.Clone(dst).(*testproto.User)
+				err := src.CopyMasked(actual, filter)
+				if expectedErr != nil {
+					assert.EqualError(t, err, expectedErr.Error())
+					return
+				}
+				require.NoError(t, err)
+				assert.True(t, proto.Equal(expected, actual), "expected %v, got %v", expected, actual)
+			})
+		}
+	}
+}
+
+func TestUser_ToMapMasked(t *testing.T) {
+	src, dsts := generatedTestUsers()
+	for filterName, filter := range generatedTestFilters() {
+		for dstName, dst := range dsts {
+			t.Run(filterName+"/"+dstName, func(t *testing.T) {
+				expected := make(map[string]interface{})
+				require.NoError(t, fieldmask_utils.StructToMap(fieldmask_utils.Mask{}, dst, expected))
+				require.NoError(t, fieldmask_utils.StructToMap(filter, src, expected))
+				actual := make(map[string]interface{})
+				require.NoError(t, fieldmask_utils.StructToMap(fieldmask_utils.Mask{}, dst, actual))
+				require.NoError(t, src.ToMapMasked(actual, filter))
+				assert.Equal(t, expected, actual)
+			})
+		}
+	}
+}
+
+func TestUser_CopyMasked_NilDst(t *testing.T) {
+	err := testUserFull.CopyMasked(nil, fieldmask_utils.Mask{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,757 @@
+package fieldmask_utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FilterJSON copies the JSON document read from `r` to `w` keeping only the members selected by the given FieldFilter.
+// The document is processed token by token, so the memory used does not depend on its size. The filter is applied the
+// same way MapToMap applies it: the objects are treated as structs, the sub-filter of an array is applied to every item
+// of it (the nested arrays are transparent) and other values are copied as is. Numbers are copied verbatim, strings
+// and the structure are re-encoded without insignificant whitespace.
+// `r` may hold a stream of JSON values (e.g. newline-delimited JSON): each of them is filtered and written followed
+// by a newline. If an error is returned some of the output may have already been written to `w`.
+func FilterJSON(filter FieldFilter, r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	out := bufio.NewWriter(w)
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := filterJSONValue(filter, token, dec, out); err != nil {
+			return err
+		}
+		out.WriteByte('\n')
+	}
+	return errors.WithStack(out.Flush())
+}
+
+// filterJSONValue writes the JSON value which starts with the given token to `w` using the filter. The rest of the
+// value is read from the decoder.
+func filterJSONValue(filter FieldFilter, token json.Token, dec *json.Decoder, w *bufio.Writer) error {
+	switch token {
+	case json.Delim('{'):
+		w.WriteByte('{')
+		first := true
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			value, err := dec.Token()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			subFilter, ok := filter.Filter(key.(string))
+			if !ok {
+				if err := skipJSONValue(value, dec); err != nil {
+					return err
+				}
+				continue
+			}
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			writeJSONString(w, key.(string), false)
+			w.WriteByte(':')
+			if err := filterJSONValue(subFilter, value, dec, w); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return errors.WithStack(err)
+		}
+		w.WriteByte('}')
+
+	case json.Delim('['):
+		itemsFilter, ok := itemFilter(filter)
+		w.WriteByte('[')
+		first := true
+		for dec.More() {
+			item, err := dec.Token()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if !ok {
+				if err := skipJSONValue(item, dec); err != nil {
+					return err
+				}
+				continue
+			}
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			if err := filterJSONValue(itemsFilter, item, dec, w); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return errors.WithStack(err)
+		}
+		w.WriteByte(']')
+
+	default:
+		writeJSONToken(w, token)
+	}
+	return nil
+}
+
+// skipJSONValue reads the rest of the JSON value which starts with the given token from the decoder.
+func skipJSONValue(token json.Token, dec *json.Decoder) error {
+	if token != json.Delim('{') && token != json.Delim('[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// writeJSONToken writes the given scalar JSON token to `w`.
+func writeJSONToken(w *bufio.Writer, token json.Token) {
+	switch token := token.(type) {
+	case string:
+		writeJSONString(w, token, false)
+	case json.Number:
+		w.WriteString(token.String())
+	case bool:
+		if token {
+			w.WriteString("true")
+		} else {
+			w.WriteString("false")
+		}
+	case nil:
+		w.WriteString("null")
+	}
+}
+
+// jsonWriter is implemented by both bufio.Writer and bytes.Buffer.
+type jsonWriter interface {
+	io.ByteWriter
+	io.StringWriter
+	WriteRune(r rune) (int, error)
+}
+
+// writeJSONString writes the given string to `w` as a JSON string. The HTML characters are escaped the same way
+// json.Marshal escapes them if escapeHTML is true.
+func writeJSONString(w jsonWriter, s string, escapeHTML bool) {
+	const hex = "0123456789abcdef"
+	w.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			w.WriteByte('\\')
+			w.WriteRune(r)
+		case r == '\n':
+			w.WriteString(`\n`)
+		case r == '\r':
+			w.WriteString(`\r`)
+		case r == '\t':
+			w.WriteString(`\t`)
+		case r < 0x20 || r == '\u2028' || r == '\u2029' || escapeHTML && (r == '<' || r == '>' || r == '&'):
+			w.WriteString(`\u`)
+			for shift := 12; shift >= 0; shift -= 4 {
+				w.WriteByte(hex[r>>uint(shift)&0xf])
+			}
+		default:
+			w.WriteRune(r)
+		}
+	}
+	w.WriteByte('"')
+}
+
+// MarshalJSON returns the JSON encoding of the fields of `v` selected by the given FieldFilter. The fields are
+// selected the same way StructToMap selects them, but they are encoded directly without building an intermediate map.
+// The json tags of the struct fields are honoured the same way json.Marshal honours them: the key names, "-", the
+// "omitempty" and the "string" options (the filter uses the field names according to the SrcTag option though, e.g.
+// WithSrcTag("json")). The fields are encoded in the order of the struct and the map keys are sorted.
+// The fields of the embedded structs without a json name are promoted to the embedding struct the same way json.Marshal
+// promotes them (including the rules for the conflicting names): the filter selects them by their own names, e.g.
+// "Id" rather than "Base{Id}".
+// The json.Marshaler and encoding.TextMarshaler implementations are used for the values selected with an empty filter,
+// the fields of the values selected with a non-empty filter are encoded one by one.
+func MarshalJSON(filter FieldFilter, v interface{}, userOpts ...Option) ([]byte, error) {
+	opts := newDefaultOptions()
+	for _, o := range userOpts {
+		o(opts)
+	}
+	var buf bytes.Buffer
+	if err := marshalJSONValue(&buf, filter, reflect.ValueOf(v), false, opts); err != nil {
+		return nil, err
+	}
+	if err := checkStrictPaths(filter, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonNumberType    = reflect.TypeOf(json.Number(""))
+)
+
+// marshalJSONValue writes the JSON encoding of the given value to `buf` using the filter. Scalar values are enclosed
+// in a JSON string if quoted is true, see the "string" option of the json tags.
+func marshalJSONValue(buf *bytes.Buffer, filter FieldFilter, v reflect.Value, quoted bool, userOptions *options) error {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+	if filter.IsEmpty() {
+		if handled, err := marshalJSONMarshaler(buf, v); handled {
+			return err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return marshalJSONValue(buf, filter, v.Elem(), quoted, userOptions)
+
+	case reflect.Struct:
+		return marshalJSONStruct(buf, filter, v, userOptions)
+
+	case reflect.Map:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return marshalJSONMap(buf, filter, v, userOptions)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			buf.WriteByte('"')
+			buf.WriteString(base64.StdEncoding.EncodeToString(v.Bytes()))
+			buf.WriteByte('"')
+			return nil
+		}
+		buf.WriteByte('[')
+		if itemsFilter, ok := itemFilter(filter); ok {
+			length := userOptions.CopyListSize(&v)
+			for i := 0; i < length; i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				if err := marshalJSONValue(buf, itemsFilter, v.Index(i), false, userOptions); err != nil {
+					return err
+				}
+			}
+		}
+		buf.WriteByte(']')
+
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			number := v.String()
+			if number == "" {
+				number = "0"
+			}
+			if !json.Valid([]byte(number)) {
+				return errors.Errorf("invalid number literal %q", number)
+			}
+			writeJSONScalar(buf, number, quoted)
+			return nil
+		}
+		if quoted {
+			var s bytes.Buffer
+			writeJSONString(&s, v.String(), true)
+			writeJSONString(buf, s.String(), true)
+			return nil
+		}
+		writeJSONString(buf, v.String(), true)
+
+	case reflect.Bool:
+		writeJSONScalar(buf, strconv.FormatBool(v.Bool()), quoted)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeJSONScalar(buf, strconv.FormatInt(v.Int(), 10), quoted)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeJSONScalar(buf, strconv.FormatUint(v.Uint(), 10), quoted)
+
+	case reflect.Float32, reflect.Float64:
+		number, err := formatJSONFloat(v.Float(), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		writeJSONScalar(buf, number, quoted)
+
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// marshalJSONMarshaler writes the JSON encoding of the given value to `buf` if it (or the pointer to it) implements
+// json.Marshaler or encoding.TextMarshaler. Result is false otherwise.
+func marshalJSONMarshaler(buf *bytes.Buffer, v reflect.Value) (bool, error) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		if ptrType := reflect.PtrTo(v.Type()); ptrType.Implements(jsonMarshalerType) ||
+			ptrType.Implements(textMarshalerType) {
+			v = v.Addr()
+		}
+	}
+	if !v.CanInterface() {
+		return false, nil
+	}
+	switch m := v.Interface().(type) {
+	case json.Marshaler:
+		data, err := m.MarshalJSON()
+		if err != nil {
+			return true, errors.WithStack(err)
+		}
+		return true, errors.WithStack(json.Compact(buf, data))
+	case encoding.TextMarshaler:
+		text, err := m.MarshalText()
+		if err != nil {
+			return true, errors.WithStack(err)
+		}
+		writeJSONString(buf, string(text), true)
+		return true, nil
+	}
+	return false, nil
+}
+
+// marshalJSONStruct writes the JSON object with the fields of the given struct selected by the filter to `buf`.
+func marshalJSONStruct(buf *bytes.Buffer, filter FieldFilter, v reflect.Value, userOptions *options) error {
+	structType := v.Type()
+	fields := jsonStructFields(structType, userOptions.SrcTag)
+	if userOptions.pathTracker != nil {
+		userOptions.pathTracker.visitStruct(filter, structType, userOptions)
+		var promoted []string
+		for _, f := range fields {
+			if len(f.index) > 1 {
+				promoted = append(promoted, f.filterName)
+			}
+		}
+		if len(promoted) > 0 {
+			userOptions.pathTracker.visit(filter, promoted, nil)
+		}
+	}
+	buf.WriteByte('{')
+	first := true
+	writeKey := func(name string) {
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		writeJSONString(buf, name, true)
+		buf.WriteByte(':')
+	}
+	for _, f := range fields {
+		field := f.field
+		fieldValue, ok := jsonFieldByIndex(v, f.index)
+		if !ok {
+			// The field is promoted from a nil embedded pointer.
+			continue
+		}
+		if isOneof(field) {
+			members, selected := oneofJSONMembers(filter, f.owner, field, fieldValue, userOptions)
+			if selected {
+				srcCase, srcOk := oneofCase(fieldValue)
+				for _, member := range members {
+					subFilter, ok := oneofMemberFilter(filter, fieldName(userOptions.SrcTag, field),
+						fieldName(userOptions.SrcTag, member))
+					if !ok {
+						continue
+					}
+					memberName, _, _, _ := jsonFieldOptions(member)
+					writeKey(memberName)
+					if !srcOk || srcCase.Name != member.Name {
+						buf.WriteString("null")
+						continue
+					}
+					if err := marshalJSONValue(buf, subFilter, fieldValue.Elem().Elem().Field(0), false,
+						userOptions); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+
+		subFilter, ok := filter.Filter(f.filterName)
+		if !ok {
+			continue
+		}
+		if f.omitEmpty && isEmptyJSONValue(fieldValue) {
+			continue
+		}
+		writeKey(f.name)
+		if err := marshalJSONValue(buf, subFilter, fieldValue, f.quoted, userOptions); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// jsonField is a field of a struct encoded by MarshalJSON.
+type jsonField struct {
+	// field is the struct field, index is its index sequence in the encoded struct (longer than 1 for the promoted
+	// fields of the embedded structs).
+	field reflect.StructField
+	index []int
+	// owner is the struct type which declares the field.
+	owner reflect.Type
+	// name is the JSON object key, filterName is the name the filter selects the field by according to the SrcTag.
+	name, filterName string
+	// tagged is true if the name is set by the json tag.
+	tagged            bool
+	omitEmpty, quoted bool
+}
+
+// jsonFieldsKey identifies the []jsonField computed for a struct type.
+type jsonFieldsKey struct {
+	structType reflect.Type
+	srcTag     string
+}
+
+// jsonFieldsCache holds the []jsonField for every jsonFieldsKey seen so far, see copyFieldsCache.
+var jsonFieldsCache sync.Map
+
+// jsonStructFields returns the fields of the given struct type encoded in JSON in the order of the struct. The fields
+// of the embedded structs without a json name are promoted following the rules of json.Marshal: a field at a shallower
+// depth hides the deeper ones with the same name, and the fields at the same depth conflict (and are omitted) unless
+// exactly one of them is tagged.
+func jsonStructFields(structType reflect.Type, srcTag string) []jsonField {
+	key := jsonFieldsKey{structType: structType, srcTag: srcTag}
+	if fields, ok := jsonFieldsCache.Load(key); ok {
+		return fields.([]jsonField)
+	}
+	type embedded struct {
+		structType reflect.Type
+		index      []int
+	}
+	var candidates []jsonField
+	// visited holds the struct types embedded at the shallower depths: their fields at the deeper ones are hidden. The
+	// same type embedded twice at the same depth results in the conflicting fields.
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{structType: structType}}; len(next) > 0; {
+		current := next
+		next = nil
+		// The fields of the same depth are collected before any of the deeper ones.
+		for _, e := range current {
+			if visited[e.structType] {
+				continue
+			}
+			for i := 0; i < e.structType.NumField(); i++ {
+				field := e.structType.Field(i)
+				fieldType := field.Type
+				if fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if field.Anonymous {
+					if !isExported(field) && fieldType.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !isExported(field) {
+					continue
+				}
+				name, omitEmpty, quoted, ok := jsonFieldOptions(field)
+				if !ok {
+					continue
+				}
+				index := append(append([]int(nil), e.index...), i)
+				tagged := strings.Split(field.Tag.Get("json"), ",")[0] != ""
+				if field.Anonymous && !tagged && fieldType.Kind() == reflect.Struct {
+					next = append(next, embedded{structType: fieldType, index: index})
+					continue
+				}
+				candidates = append(candidates, jsonField{
+					field:      field,
+					index:      index,
+					owner:      e.structType,
+					name:       name,
+					filterName: fieldName(srcTag, field),
+					tagged:     tagged,
+					omitEmpty:  omitEmpty,
+					quoted:     quoted,
+				})
+			}
+		}
+		for _, e := range current {
+			visited[e.structType] = true
+		}
+	}
+
+	// The candidates are ordered by depth, so the dominant field of a name is among the first ones with that name.
+	byName := make(map[string][]jsonField)
+	for _, f := range candidates {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fields := make([]jsonField, 0, len(candidates))
+	for _, f := range candidates {
+		if dominant, ok := dominantJSONField(byName[f.name]); ok && sameIndex(dominant.index, f.index) {
+			fields = append(fields, f)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	cached, _ := jsonFieldsCache.LoadOrStore(key, fields)
+	return cached.([]jsonField)
+}
+
+// dominantJSONField returns the field that wins among the given fields with the same JSON name (ordered by depth).
+// Result is false if none of them wins.
+func dominantJSONField(fields []jsonField) (jsonField, bool) {
+	depth := len(fields[0].index)
+	var dominant []jsonField
+	for _, f := range fields {
+		if len(f.index) > depth {
+			break
+		}
+		if f.tagged {
+			dominant = append(dominant, f)
+		}
+	}
+	if len(dominant) == 0 {
+		for _, f := range fields {
+			if len(f.index) > depth {
+				break
+			}
+			dominant = append(dominant, f)
+		}
+	}
+	if len(dominant) != 1 {
+		return jsonField{}, false
+	}
+	return dominant[0], true
+}
+
+// sameIndex returns true if the given index sequences are equal.
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFieldByIndex returns the nested field of the given struct by its index sequence. Result is false if any of the
+// embedded pointers on the way is nil.
+func jsonFieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// oneofJSONMembers returns the members of the given oneof field declared by the given struct type and whether the
+// filter mentions any of them directly, in which case the members are encoded as the fields of the struct, see
+// oneofToMap. The value is the one of the oneof field.
+func oneofJSONMembers(filter FieldFilter, owner reflect.Type, field reflect.StructField, value reflect.Value,
+	userOptions *options) ([]reflect.StructField, bool) {
+	members := oneofMemberFields(owner, field)
+	if srcCase, ok := oneofCase(value); ok && !hasStructField(members, srcCase.Name) {
+		members = append(members, srcCase)
+	}
+	for _, member := range members {
+		if mentionsField(filter, fieldName(userOptions.SrcTag, member)) {
+			return members, true
+		}
+	}
+	return nil, false
+}
+
+// marshalJSONMap writes the JSON object with the entries of the given map selected by the filter to `buf`. The keys
+// are sorted.
+func marshalJSONMap(buf *bytes.Buffer, filter FieldFilter, v reflect.Value, userOptions *options) error {
+	type entry struct {
+		// key is the JSON object key, filterKey is the key the filter is applied to, see mapKeyString.
+		key, filterKey string
+		value          reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := jsonMapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, filterKey: mapKeyString(iter.Key()), value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	buf.WriteByte('{')
+	first := true
+	for _, e := range entries {
+		var subFilter FieldFilter = Mask{}
+		ok := true
+		if !filter.IsEmpty() {
+			subFilter, ok = mapKeyFilter(filter, e.filterKey)
+		}
+		if !ok {
+			continue
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		writeJSONString(buf, e.key, true)
+		buf.WriteByte(':')
+		if err := marshalJSONValue(buf, subFilter, e.value, false, userOptions); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// jsonMapKey returns the JSON object key of the given map key the same way json.Marshal does.
+func jsonMapKey(key reflect.Value) (string, error) {
+	if m, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Ptr && key.IsNil() {
+			return "", nil
+		}
+		text, err := m.MarshalText()
+		return string(text), errors.WithStack(err)
+	}
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", errors.Errorf("unsupported map key type %s", key.Type())
+}
+
+// jsonFieldOptions returns the name of the given struct field in JSON and the options of its json tag. Result is
+// false if the field is omitted ("-").
+func jsonFieldOptions(field reflect.StructField) (name string, omitEmpty, quoted, ok bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false, false
+	}
+	options := strings.Split(tag, ",")
+	name = options[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range options[1:] {
+		switch option {
+		case "omitempty":
+			omitEmpty = true
+		case "string":
+			quoted = isJSONScalar(field.Type)
+		}
+	}
+	return name, omitEmpty, quoted, true
+}
+
+// isJSONScalar returns true if the "string" option of the json tags applies to the values of the given type.
+func isJSONScalar(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isEmptyJSONValue returns true if the given value is omitted by the "omitempty" option of the json tags.
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// writeJSONScalar writes the given JSON literal to `buf` enclosing it in a JSON string if quoted is true.
+func writeJSONScalar(buf *bytes.Buffer, literal string, quoted bool) {
+	if quoted {
+		buf.WriteByte('"')
+	}
+	buf.WriteString(literal)
+	if quoted {
+		buf.WriteByte('"')
+	}
+}
+
+// formatJSONFloat formats the given floating point number of the given bit size the same way json.Marshal does.
+func formatJSONFloat(f float64, bits int) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", errors.Errorf("unsupported value: %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return string(b), nil
+}
@@ -0,0 +1,374 @@
+package fieldmask_utils_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestFilterJSON(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   fieldmask_utils.FieldFilter
+		expected string
+	}{
+		{
+			name:   "empty mask",
+			filter: fieldmask_utils.Mask{},
+			expected: `{"id":1,"name":"John","address":{"city":"Paris","zip":"75001"},` +
+				`"orders":[{"id":1,"items":[{"sku":"a","qty":1}],"total":10},` +
+				`{"id":2,"items":[{"sku":"b","qty":2},{"sku":"c","qty":3}],"total":20}],` +
+				`"matrix":[[{"x":1,"y":2}],[{"x":3,"y":4}]],"tags":["a","b"]}`,
+		},
+		{
+			name:     "top level keys",
+			filter:   fieldmask_utils.MaskFromString("tags,id,unknown"),
+			expected: `{"id":1,"tags":["a","b"]}`,
+		},
+		{
+			name:     "nested object",
+			filter:   fieldmask_utils.MaskFromString("address{city}"),
+			expected: `{"address":{"city":"Paris"}}`,
+		},
+		{
+			name:   "arrays",
+			filter: fieldmask_utils.MaskFromString("orders{id,items{sku}},matrix{x}"),
+			expected: `{"orders":[{"id":1,"items":[{"sku":"a"}]},{"id":2,"items":[{"sku":"b"},{"sku":"c"}]}],` +
+				`"matrix":[[{"x":1}],[{"x":3}]]}`,
+		},
+		{
+			name: "inverse",
+			filter: fieldmask_utils.MaskInverse{
+				"address": nil,
+				"orders":  fieldmask_utils.MaskInverse{"items": nil},
+				"matrix":  nil,
+			},
+			expected: `{"id":1,"name":"John","orders":[{"id":1,"total":10},{"id":2,"total":20}],"tags":["a","b"]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := fieldmask_utils.FilterJSON(tc.filter, strings.NewReader(mapToMapDocument), &out)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected+"\n", out.String())
+
+			// The result is the same as the one of MapToMap.
+			dst := make(map[string]interface{})
+			require.NoError(t, fieldmask_utils.MapToMap(tc.filter, decodeJSONMap(t, mapToMapDocument), dst))
+			assert.Equal(t, dst, decodeJSONMap(t, out.String()))
+		})
+	}
+}
+
+func TestFilterJSON_Values(t *testing.T) {
+	var out bytes.Buffer
+	input := `{"n": 12345678901234567890.5e-1, "s": "<a href=\"x\">é\n\u0001\u2028</a>", "b": [true, false, null]}`
+	err := fieldmask_utils.FilterJSON(fieldmask_utils.Mask{}, strings.NewReader(input), &out)
+	require.NoError(t, err)
+	expected := `{"n":12345678901234567890.5e-1,"s":"<a href=\"x\">é\n\u0001\u2028</a>","b":[true,false,null]}`
+	assert.Equal(t, expected+"\n", out.String())
+}
+
+func TestFilterJSON_Stream(t *testing.T) {
+	var out bytes.Buffer
+	input := "{\"a\": 1, \"b\": 2}\n[{\"a\": 3, \"b\": 4}]\n\"c\"\n"
+	err := fieldmask_utils.FilterJSON(fieldmask_utils.MaskFromString("a"), strings.NewReader(input), &out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n[{\"a\":3}]\n\"c\"\n", out.String())
+}
+
+func TestFilterJSON_InvalidJSON(t *testing.T) {
+	for _, input := range []string{`{"a": 1`, `{"a": }`, `[1, 2`, `{"a": [1}`} {
+		t.Run(input, func(t *testing.T) {
+			err := fieldmask_utils.FilterJSON(fieldmask_utils.MaskFromString("a"), strings.NewReader(input),
+				&bytes.Buffer{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMarshalJSON_SameAsStructToMap(t *testing.T) {
+	masks := []string{
+		"Id,Username,Role,Deactivated",
+		"Avatar{OriginalUrl}",
+		"Images{ResizedUrl},Tags",
+		"Meta,Permissions",
+		"MaleName",
+		"Name",
+		"ExtraUser",
+		"Details",
+		"Friends{Id,Username}",
+	}
+	for _, m := range masks {
+		t.Run(m, func(t *testing.T) {
+			mask := fieldmask_utils.MaskFromString(m)
+			dst := make(map[string]interface{})
+			require.NoError(t, fieldmask_utils.StructToMap(mask, testUserFull, dst, fieldmask_utils.WithTag("json")))
+			expected, err := json.Marshal(dst)
+			require.NoError(t, err)
+
+			actual, err := fieldmask_utils.MarshalJSON(mask, testUserFull)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+type marshalJSONNested struct {
+	A int    `json:"a"`
+	B string `json:"b,omitempty"`
+}
+
+type marshalJSONStruct struct {
+	Name       string                        `json:"name"`
+	Ignored    string                        `json:"-"`
+	Dash       string                        `json:"-,"`
+	Empty      string                        `json:"empty,omitempty"`
+	Quoted     int64                         `json:"quoted,string"`
+	QuotedStr  string                        `json:"quoted_str,string"`
+	QuotedPtr  *bool                         `json:"quoted_ptr,string"`
+	Float      float64                       `json:"float"`
+	Small      float32                       `json:"small"`
+	Number     json.Number                   `json:"number"`
+	Bytes      []byte                        `json:"bytes"`
+	NilSlice   []int                         `json:"nil_slice"`
+	Time       time.Time                     `json:"time"`
+	IP         net.IP                        `json:"ip"`
+	Nested     marshalJSONNested             `json:"nested"`
+	NestedPtr  *marshalJSONNested            `json:"nested_ptr"`
+	Items      []marshalJSONNested           `json:"items"`
+	ByKey      map[string]*marshalJSONNested `json:"by_key"`
+	ByInt      map[int]string                `json:"by_int"`
+	ByIP       map[textKey]int               `json:"by_ip"`
+	Any        interface{}                   `json:"any"`
+	NoTag      string
+	unexported string
+}
+
+type textKey string
+
+func (k textKey) MarshalText() ([]byte, error) {
+	return []byte("ip:" + string(k)), nil
+}
+
+func newMarshalJSONStruct() *marshalJSONStruct {
+	yes := true
+	return &marshalJSONStruct{
+		Name:       "<name> & co",
+		Ignored:    "ignored",
+		Dash:       "dash",
+		Quoted:     42,
+		QuotedStr:  `say "hi"`,
+		QuotedPtr:  &yes,
+		Float:      1e-7,
+		Small:      3.14,
+		Number:     "12.50",
+		Bytes:      []byte("bytes"),
+		Time:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		IP:         net.ParseIP("127.0.0.1"),
+		Nested:     marshalJSONNested{A: 1, B: "b"},
+		Items:      []marshalJSONNested{{A: 1}, {A: 2, B: "b"}},
+		ByKey:      map[string]*marshalJSONNested{"x": {A: 1, B: "x"}, "y": nil, "a": {A: 2}},
+		ByInt:      map[int]string{10: "ten", 2: "two"},
+		ByIP:       map[textKey]int{"1": 1},
+		Any:        map[string]interface{}{"foo": []interface{}{1, "bar"}},
+		NoTag:      "no tag",
+		unexported: "unexported",
+	}
+}
+
+func TestMarshalJSON_SameAsJSONMarshal(t *testing.T) {
+	v := newMarshalJSONStruct()
+	expected, err := json.Marshal(v)
+	require.NoError(t, err)
+	actual, err := fieldmask_utils.MarshalJSON(fieldmask_utils.Mask{}, v)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestMarshalJSON_Mask(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   fieldmask_utils.FieldFilter
+		opts     []fieldmask_utils.Option
+		expected string
+	}{
+		{
+			name:   "fields",
+			filter: fieldmask_utils.MaskFromString("Name,Empty,Nested{A},NestedPtr,Items{B},Time,Ignored"),
+			expected: `{"name":"\u003cname\u003e \u0026 co","time":"2020-01-02T03:04:05Z","nested":{"a":1},` +
+				`"nested_ptr":null,"items":[{},{"b":"b"}]}`,
+		},
+		{
+			name:     "map keys",
+			filter:   fieldmask_utils.MaskFromString("ByKey{x{B},y,z},ByInt{10}"),
+			expected: `{"by_key":{"x":{"b":"x"},"y":null},"by_int":{"10":"ten"}}`,
+		},
+		{
+			name:     "src tag",
+			filter:   fieldmask_utils.MaskFromString("quoted,nested{b},NoTag"),
+			opts:     []fieldmask_utils.Option{fieldmask_utils.WithSrcTag("json")},
+			expected: `{"quoted":"42","nested":{"b":"b"},"NoTag":"no tag"}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := fieldmask_utils.MarshalJSON(tc.filter, newMarshalJSONStruct(), tc.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+func TestMarshalJSON_MaskInverse(t *testing.T) {
+	v := newMarshalJSONStruct()
+	expected := make(map[string]interface{})
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &expected))
+	delete(expected, "name")
+	delete(expected["nested"].(map[string]interface{}), "b")
+
+	actual, err := fieldmask_utils.MarshalJSON(
+		fieldmask_utils.MaskInverse{"Name": nil, "Nested": fieldmask_utils.MaskInverse{"B": nil}}, v)
+	require.NoError(t, err)
+	expectedData, err := json.Marshal(expected)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expectedData), string(actual))
+}
+
+func TestMarshalJSON_StrictPaths(t *testing.T) {
+	_, err := fieldmask_utils.MarshalJSON(fieldmask_utils.MaskFromString("Name,Nested{C}"), newMarshalJSONStruct(),
+		fieldmask_utils.WithStrictPaths())
+	var unknownPathsErr *fieldmask_utils.UnknownPathsError
+	require.ErrorAs(t, err, &unknownPathsErr)
+	assert.Equal(t, []string{"Nested.C"}, unknownPathsErr.Paths)
+}
+
+type MarshalJSONBase struct {
+	ID   int
+	Name string `json:"name"`
+	Deep MarshalJSONDeep
+}
+
+type MarshalJSONDeep struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+type MarshalJSONOther struct {
+	ID    int
+	Other string `json:"other"`
+}
+
+type MarshalJSONInt int
+
+type marshalJSONHidden struct {
+	Hidden string `json:"hidden"`
+}
+
+type marshalJSONEmbedding struct {
+	MarshalJSONBase
+	*MarshalJSONDeep
+	MarshalJSONOther
+	MarshalJSONInt
+	marshalJSONHidden
+	Tagged MarshalJSONDeep `json:"tagged"`
+	Name   string          `json:"name"`
+}
+
+func TestMarshalJSON_EmbeddedStructs(t *testing.T) {
+	testCases := []struct {
+		name string
+		v    *marshalJSONEmbedding
+	}{
+		{"nil embedded pointer", &marshalJSONEmbedding{
+			MarshalJSONBase:   MarshalJSONBase{ID: 1, Name: "base", Deep: MarshalJSONDeep{Level: 1}},
+			MarshalJSONOther:  MarshalJSONOther{ID: 2, Other: "other"},
+			MarshalJSONInt:    3,
+			marshalJSONHidden: marshalJSONHidden{Hidden: "hidden"},
+			Name:              "name",
+		}},
+		{"embedded pointer", &marshalJSONEmbedding{
+			MarshalJSONDeep: &MarshalJSONDeep{Name: "deep", Level: 4},
+			Tagged:          MarshalJSONDeep{Level: 5},
+		}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			expected, err := json.Marshal(testCase.v)
+			require.NoError(t, err)
+			actual, err := fieldmask_utils.MarshalJSON(fieldmask_utils.Mask{}, testCase.v)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+
+			// The promoted fields are selected by their own names.
+			mask := fieldmask_utils.MaskFromString("Name,Level,Other,Hidden,Deep")
+			filtered, err := fieldmask_utils.MarshalJSON(mask, testCase.v, fieldmask_utils.WithStrictPaths())
+			require.NoError(t, err)
+			var expectedMap map[string]interface{}
+			require.NoError(t, json.Unmarshal(expected, &expectedMap))
+			delete(expectedMap, "MarshalJSONInt")
+			delete(expectedMap, "tagged")
+			assert.JSONEq(t, string(mustMarshalJSON(t, expectedMap)), string(filtered))
+
+			// The conflicting fields are omitted.
+			_, err = fieldmask_utils.MarshalJSON(fieldmask_utils.MaskFromString("ID"), testCase.v,
+				fieldmask_utils.WithStrictPaths())
+			assert.EqualError(t, err, "unknown paths: ID")
+		})
+	}
+}
+
+func TestMarshalJSON_EmbeddedOneof(t *testing.T) {
+	v := struct {
+		*testproto.User
+		Extra string
+	}{User: testUserFull, Extra: "extra"}
+	expected, err := fieldmask_utils.MarshalJSON(fieldmask_utils.MaskFromString("Id,MaleName,FemaleName"), testUserFull)
+	require.NoError(t, err)
+	var expectedMap map[string]interface{}
+	require.NoError(t, json.Unmarshal(expected, &expectedMap))
+	expectedMap["Extra"] = "extra"
+
+	actual, err := fieldmask_utils.MarshalJSON(fieldmask_utils.MaskFromString("Id,MaleName,FemaleName,Extra"), v)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(mustMarshalJSON(t, expectedMap)), string(actual))
+
+	// The fields promoted from a nil embedded pointer are omitted.
+	v.User = nil
+	actual, err = fieldmask_utils.MarshalJSON(fieldmask_utils.MaskFromString("Id,MaleName,Extra"), v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Extra": "extra"}`, string(actual))
+}
+
+func mustMarshalJSON(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func TestMarshalJSON_Errors(t *testing.T) {
+	testCases := []interface{}{
+		struct{ F float64 }{F: math.NaN()},
+		struct{ F float64 }{F: math.Inf(1)},
+		struct{ C chan int }{C: make(chan int)},
+		struct{ N json.Number }{N: "1.2.3"},
+		map[float64]int{1: 1},
+	}
+	for _, v := range testCases {
+		_, err := fieldmask_utils.MarshalJSON(fieldmask_utils.Mask{}, v)
+		assert.Error(t, err, "%#v", v)
+	}
+}
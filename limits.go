@@ -0,0 +1,142 @@
+package fieldmask_utils
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMaskTooLarge is matched by errors.Is for the errors returned when a mask exceeds one of the limits set with the
+// ParseOption functions. Use errors.As with *MaskTooLargeError to find out which limit is exceeded.
+var ErrMaskTooLarge = errors.New("mask is too large")
+
+// MaskTooLargeError is returned when a mask exceeds one of the limits set with the ParseOption functions.
+type MaskTooLargeError struct {
+	// Limit is the name of the exceeded limit: "paths", "depth", "segment length" or "nodes".
+	Limit string
+	// Max is the value of the exceeded limit.
+	Max int
+}
+
+// Error implements the error interface.
+func (e *MaskTooLargeError) Error() string {
+	return fmt.Sprintf("mask is too large: %s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// Is makes the error match ErrMaskTooLarge.
+func (e *MaskTooLargeError) Is(target error) bool {
+	return target == ErrMaskTooLarge
+}
+
+// ParseOptions limit the size of a mask built from paths, which is useful when the paths come from an untrusted
+// client, e.g. in a google.protobuf.FieldMask of a request. Zero values mean no limit.
+type ParseOptions struct {
+	// MaxPaths is the maximum number of paths.
+	MaxPaths int
+	// MaxDepth is the maximum number of segments in a path.
+	MaxDepth int
+	// MaxSegmentLength is the maximum length of a path segment in bytes (after unquoting).
+	MaxSegmentLength int
+	// MaxNodes is the maximum total number of the field names in the resulting mask tree.
+	MaxNodes int
+}
+
+// ParseOption modifies the ParseOptions used by MaskFromPathsWithOptions, ParseMask, FieldFilterFromPathsFor and the
+// like.
+type ParseOption func(*ParseOptions)
+
+// WithMaxPaths limits the number of paths.
+func WithMaxPaths(n int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxPaths = n
+	}
+}
+
+// WithMaxDepth limits the number of segments in a path.
+func WithMaxDepth(n int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxDepth = n
+	}
+}
+
+// WithMaxSegmentLength limits the length of a path segment in bytes.
+func WithMaxSegmentLength(n int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxSegmentLength = n
+	}
+}
+
+// WithMaxNodes limits the total number of the field names in the resulting mask tree.
+func WithMaxNodes(n int) ParseOption {
+	return func(o *ParseOptions) {
+		o.MaxNodes = n
+	}
+}
+
+// WithParseOptions sets all the limits at once.
+func WithParseOptions(parseOptions ParseOptions) ParseOption {
+	return func(o *ParseOptions) {
+		*o = parseOptions
+	}
+}
+
+// pathsLimiter enforces the ParseOptions while a mask is built from paths. The limits are checked while the input is
+// scanned, so that an oversized input is rejected as soon as a limit is exceeded.
+type pathsLimiter struct {
+	ParseOptions
+	paths int
+	nodes int
+}
+
+func newPathsLimiter(opts []ParseOption) *pathsLimiter {
+	l := &pathsLimiter{}
+	for _, o := range opts {
+		o(&l.ParseOptions)
+	}
+	return l
+}
+
+// checkPaths checks the number of paths.
+func (l *pathsLimiter) checkPaths(paths []string) error {
+	if l.MaxPaths > 0 && len(paths) > l.MaxPaths {
+		return &MaskTooLargeError{Limit: "paths", Max: l.MaxPaths}
+	}
+	return nil
+}
+
+// addPath counts a path found while the input is scanned.
+func (l *pathsLimiter) addPath() error {
+	l.paths++
+	if l.MaxPaths > 0 && l.paths > l.MaxPaths {
+		return &MaskTooLargeError{Limit: "paths", Max: l.MaxPaths}
+	}
+	return nil
+}
+
+// checkDepth checks the number of segments in a path.
+func (l *pathsLimiter) checkDepth(depth int) error {
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return &MaskTooLargeError{Limit: "depth", Max: l.MaxDepth}
+	}
+	return nil
+}
+
+// checkSegmentLength checks the length of a path segment.
+func (l *pathsLimiter) checkSegmentLength(length int) error {
+	if l.MaxSegmentLength > 0 && length > l.MaxSegmentLength {
+		return &MaskTooLargeError{Limit: "segment length", Max: l.MaxSegmentLength}
+	}
+	return nil
+}
+
+// subContainer is the same as the subContainer function which also counts the created nodes.
+func (l *pathsLimiter) subContainer(container FieldFilterContainer, fieldName string, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	if subNode, ok := container.Get(fieldName); ok && subNode != nil {
+		return subNode, nil
+	}
+	l.nodes++
+	if l.MaxNodes > 0 && l.nodes > l.MaxNodes {
+		return nil, &MaskTooLargeError{Limit: "nodes", Max: l.MaxNodes}
+	}
+	return subContainer(container, fieldName, filter), nil
+}
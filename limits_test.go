@@ -0,0 +1,120 @@
+package fieldmask_utils_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/protobuf/field_mask"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestMaskFromPaths_Limits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		paths         []string
+		opts          []fieldmask_utils.ParseOption
+		expectedLimit string
+	}{
+		{"paths", []string{"a", "b", "c"}, []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxPaths(2)}, "paths"},
+		{"depth", []string{"a.b.c"}, []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxDepth(2)}, "depth"},
+		{"segment length", []string{"a.bcd"}, []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxSegmentLength(2)},
+			"segment length"},
+		{"quoted segment length", []string{"a.`b.c`"}, []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxSegmentLength(2)},
+			"segment length"},
+		{"nodes", []string{"a.b", "a.c", "d"}, []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxNodes(3)}, "nodes"},
+		{"all", []string{"a.b.c"}, []fieldmask_utils.ParseOption{
+			fieldmask_utils.WithParseOptions(fieldmask_utils.ParseOptions{MaxPaths: 1, MaxDepth: 3, MaxNodes: 2}),
+		}, "nodes"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mask, err := fieldmask_utils.MaskFromPathsWithOptions(testCase.paths, func(s string) string { return s },
+				testCase.opts...)
+			require.Error(t, err)
+			assert.Nil(t, mask)
+			assert.True(t, errors.Is(err, fieldmask_utils.ErrMaskTooLarge))
+			var tooLarge *fieldmask_utils.MaskTooLargeError
+			require.True(t, errors.As(err, &tooLarge))
+			assert.Equal(t, testCase.expectedLimit, tooLarge.Limit)
+		})
+	}
+}
+
+func TestMaskFromPaths_WithinLimits(t *testing.T) {
+	mask, err := fieldmask_utils.MaskFromPathsWithOptions([]string{"a.b", "a.c", "a", "d"},
+		func(s string) string { return s },
+		fieldmask_utils.WithMaxPaths(4), fieldmask_utils.WithMaxDepth(2), fieldmask_utils.WithMaxSegmentLength(1),
+		fieldmask_utils.WithMaxNodes(4))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("a{b,c},d"), mask)
+}
+
+func TestMaskFromProtoFieldMask_Limits(t *testing.T) {
+	fieldMask := &field_mask.FieldMask{Paths: []string{strings.Repeat("a.", 1000) + "a"}}
+	_, err := fieldmask_utils.MaskFromProtoFieldMaskWithOptions(fieldMask, func(s string) string { return s },
+		fieldmask_utils.WithMaxDepth(32))
+	assert.True(t, errors.Is(err, fieldmask_utils.ErrMaskTooLarge))
+
+	_, err = fieldmask_utils.MaskInverseFromProtoFieldMaskWithOptions(fieldMask, func(s string) string { return s },
+		fieldmask_utils.WithMaxDepth(32))
+	assert.True(t, errors.Is(err, fieldmask_utils.ErrMaskTooLarge))
+}
+
+func TestMaskFromProtoFieldMaskFor_Limits(t *testing.T) {
+	fieldMask := &field_mask.FieldMask{Paths: []string{"id", "avatar.original_url", "avatar.resized_url"}}
+	_, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, fieldMask, fieldmask_utils.WithMaxNodes(3))
+	assert.True(t, errors.Is(err, fieldmask_utils.ErrMaskTooLarge))
+
+	mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, fieldMask, fieldmask_utils.WithMaxNodes(4))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("Id,Avatar{OriginalUrl,ResizedUrl}"), mask)
+}
+
+func TestParseMask_Limits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		opts          []fieldmask_utils.ParseOption
+		expectedLimit string
+	}{
+		{"paths", "a,b{c,d}", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxPaths(2)}, "paths"},
+		{"depth", "a{b{c}}", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxDepth(2)}, "depth"},
+		{"segment length", "a{bcd}", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxSegmentLength(2)},
+			"segment length"},
+		{"quoted segment length", "a{`b,c`}", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxSegmentLength(2)},
+			"segment length"},
+		{"nodes", "a{b,c},d", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxNodes(3)}, "nodes"},
+		// The limit is reached before the syntax error at the end of the input.
+		{"malformed", strings.Repeat("a{", 1000) + "}", []fieldmask_utils.ParseOption{fieldmask_utils.WithMaxDepth(32)},
+			"depth"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mask, err := fieldmask_utils.ParseMask(testCase.input, func(s string) string { return s }, testCase.opts...)
+			require.Error(t, err)
+			assert.Nil(t, mask)
+			var tooLarge *fieldmask_utils.MaskTooLargeError
+			require.True(t, errors.As(err, &tooLarge))
+			assert.Equal(t, testCase.expectedLimit, tooLarge.Limit)
+		})
+	}
+
+	mask, err := fieldmask_utils.ParseMask("a{b,c},d", func(s string) string { return s },
+		fieldmask_utils.WithMaxPaths(3), fieldmask_utils.WithMaxDepth(2), fieldmask_utils.WithMaxSegmentLength(1),
+		fieldmask_utils.WithMaxNodes(4))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("a{b,c},d"), mask)
+}
+
+func TestMaskFromPathsWithOptions_LimitsWhileScanning(t *testing.T) {
+	// The segment length is exceeded before the unterminated quote is reached.
+	_, err := fieldmask_utils.MaskFromPathsWithOptions([]string{"a.`" + strings.Repeat("b", 1000)},
+		func(s string) string { return s }, fieldmask_utils.WithMaxSegmentLength(16))
+	assert.True(t, errors.Is(err, fieldmask_utils.ErrMaskTooLarge))
+	assert.NotContains(t, err.Error(), "bbb")
+}
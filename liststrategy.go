@@ -0,0 +1,312 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ListStrategy defines how the items of a src slice are copied to a dst slice by StructToStruct and StructToMap.
+type ListStrategy int
+
+const (
+	// MergeByIndex copies every src item to the dst item with the same index: the existing dst items are updated in
+	// place (so that the fields not selected by the filter are kept), the missing ones are appended and dst is truncated
+	// to the length of src. This is the default strategy.
+	MergeByIndex ListStrategy = iota
+	// Replace replaces dst with a new slice of the src items copied using the filter (to the zero items). A nil src
+	// slice sets dst to nil.
+	Replace
+	// Append appends the src items copied using the filter (to the zero items) to dst. The existing dst items are left
+	// intact.
+	Append
+)
+
+// String returns the name of the strategy.
+func (s ListStrategy) String() string {
+	switch s {
+	case MergeByIndex:
+		return "MergeByIndex"
+	case Replace:
+		return "Replace"
+	case Append:
+		return "Append"
+	}
+	return "ListStrategy(unknown)"
+}
+
+// listStrategyRule is the strategy of the slices at the given path.
+type listStrategyRule struct {
+	path     []pathSegment
+	strategy ListStrategy
+	// key is the name of the field the items are matched by, see WithListKey.
+	key string
+}
+
+// WithListStrategy sets an option that defines how the slices at the given path are copied, see ListStrategy.
+// An empty path sets the strategy of all the slices which do not have a strategy of their own.
+// The path consists of the (src) field names from the root struct to the slice field separated by dots, e.g.
+// "Friends.Images" for the Images of every friend: the items of a slice are not a part of the path, the keys of a map
+// are, and a "*" segment matches any key. The syntax is the same as in MaskFromPaths (segments may be enclosed in
+// backticks). The options for the same path are merged: the last strategy wins and a strategy other than MergeByIndex
+// conflicts with the key set by WithListKey. If several paths match a slice the last option wins.
+// Arrays are always merged by index.
+// The copy functions return an error if the path is invalid or the options conflict.
+func WithListStrategy(path string, strategy ListStrategy) Option {
+	return func(o *options) {
+		if path == "" {
+			o.ListStrategy = strategy
+			return
+		}
+		rule := o.listRule(path)
+		if rule == nil {
+			return
+		}
+		if rule.key != "" && strategy != MergeByIndex {
+			o.setListRulesErr(errors.Errorf("list strategy %s conflicts with list key %s for path %q", strategy,
+				rule.key, path))
+			return
+		}
+		rule.strategy = strategy
+	}
+}
+
+// WithListKey sets an option that makes StructToStruct match the items of the slices at the given path by the value of
+// the given key field instead of their index: a dst item with the same key as a src item is updated using the filter
+// (as if it had the same index), the src items with new keys are appended (with the key field set even if the filter
+// does not select it) and the dst items with the keys missing in src are removed. The resulting slice follows the order
+// of src.
+// The items must be structs (or pointers to structs) and the key is the name of their field according to the SrcTag
+// option. The key field must be of a comparable type, pointers to such types are dereferenced (nil has no key).
+// The nil items and the items with the duplicate keys are never matched.
+// The path syntax is the same as in WithListStrategy except that it must not be empty. The same can be declared with the
+// "fieldmask" tag of a slice field of the src struct, e.g. `fieldmask:"key=OriginalUrl"`: the options win over the tag.
+// StructToMap copies such slices by index.
+// The copy functions return an error if the path is invalid, the key is empty or it conflicts with the strategy set by
+// WithListStrategy for the same path.
+func WithListKey(path, key string) Option {
+	return func(o *options) {
+		if key == "" {
+			o.setListRulesErr(errors.Errorf("list key for path %q must not be empty", path))
+			return
+		}
+		rule := o.listRule(path)
+		if rule == nil {
+			return
+		}
+		if rule.strategy != MergeByIndex {
+			o.setListRulesErr(errors.Errorf("list key %s conflicts with list strategy %s for path %q", key,
+				rule.strategy, path))
+			return
+		}
+		rule.key = key
+	}
+}
+
+// listRule returns the rule of the slices at the given path. The existing rule for the same path is moved to the end of
+// the rules (so that it wins over the rules of the other matching paths), a new one is added otherwise. Nil is returned
+// if the path is invalid.
+func (o *options) listRule(path string) *listStrategyRule {
+	segments, err := splitPath(path, newPathsLimiter(nil))
+	if err != nil {
+		o.setListRulesErr(errors.Wrapf(err, "invalid list path %q", path))
+		return nil
+	}
+	rule := listStrategyRule{path: segments}
+	for i, existing := range o.listStrategies {
+		if samePath(existing.path, segments) {
+			rule = existing
+			o.listStrategies = append(o.listStrategies[:i], o.listStrategies[i+1:]...)
+			break
+		}
+	}
+	o.listStrategies = append(o.listStrategies, rule)
+	return &o.listStrategies[len(o.listStrategies)-1]
+}
+
+// setListRulesErr records the first error of the WithListStrategy and WithListKey options.
+func (o *options) setListRulesErr(err error) {
+	if o.listRulesErr == nil {
+		o.listRulesErr = err
+	}
+}
+
+// samePath returns true if the given paths consist of the same segments.
+func samePath(a, b []pathSegment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// listKeyTag is the name of the struct tag which declares the key of the slice items, see WithListKey.
+const listKeyTag = "fieldmask"
+
+// tagListKey returns the key declared in the "fieldmask" tag of the given field (if any).
+func tagListKey(f reflect.StructField) string {
+	tag, ok := f.Tag.Lookup(listKeyTag)
+	if !ok {
+		return ""
+	}
+	for _, option := range strings.Split(tag, ",") {
+		if key := strings.TrimPrefix(strings.TrimSpace(option), "key="); key != option {
+			return key
+		}
+	}
+	return ""
+}
+
+// pushPath appends the given segment to the path of the value being copied. The path is only tracked if it is needed to
+// choose the strategy of a slice.
+func (o *options) pushPath(segment string) {
+	if len(o.listStrategies) > 0 {
+		o.path = append(o.path, segment)
+	}
+}
+
+// popPath removes the last segment from the path of the value being copied, see pushPath.
+func (o *options) popPath() {
+	if len(o.listStrategies) > 0 {
+		o.path = o.path[:len(o.path)-1]
+	}
+}
+
+// listStrategy returns the strategy of the slice being copied and the key its items are matched by (if any). The given
+// key is the one declared by the tag of the field being copied.
+func (o *options) listStrategy(tagKey string) (ListStrategy, string) {
+	for i := len(o.listStrategies) - 1; i >= 0; i-- {
+		if rule := o.listStrategies[i]; pathMatches(rule.path, o.path) {
+			return rule.strategy, rule.key
+		}
+	}
+	if tagKey != "" {
+		return MergeByIndex, tagKey
+	}
+	return o.ListStrategy, ""
+}
+
+// pathMatches returns true if the given path matches the given pattern segment by segment.
+func pathMatches(pattern []pathSegment, path []string) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, segment := range pattern {
+		if !segment.isWildcard() && segment.name != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeByKey copies the items of the src slice to the dst slice matching them by the given key field, see WithListKey.
+func mergeByKey(itemsFilter FieldFilter, key string, src, dst *reflect.Value, userOptions *options) error {
+	srcType, dstType := src.Type().Elem(), dst.Type().Elem()
+	for srcType.Kind() == reflect.Ptr {
+		srcType = srcType.Elem()
+	}
+	for dstType.Kind() == reflect.Ptr {
+		dstType = dstType.Elem()
+	}
+	if srcType.Kind() != reflect.Struct || dstType.Kind() != reflect.Struct {
+		return errors.Errorf("list key %s requires struct items, got %s and %s", key, srcType, dstType)
+	}
+	var keyField *copyField
+	for _, f := range structCopyFields(srcType, dstType, userOptions) {
+		if f.srcName == key && !f.oneof {
+			f := f
+			keyField = &f
+			break
+		}
+	}
+	if keyField == nil || keyField.dstIndex == nil {
+		return errors.Errorf("list key field %s is not found in %s and %s", key, srcType, dstType)
+	}
+
+	// The items with a key duplicated in src or dst are never matched.
+	duplicates := make(map[interface{}]bool)
+	dstItems := make(map[interface{}]reflect.Value, dst.Len())
+	for i := 0; i < dst.Len(); i++ {
+		dstItem := dst.Index(i)
+		itemKey, ok, err := listItemKey(dstItem, keyField.dstIndex)
+		if err != nil {
+			return err
+		}
+		if _, seen := dstItems[itemKey]; ok && seen {
+			duplicates[itemKey] = true
+		} else if ok {
+			dstItems[itemKey] = dstItem
+		}
+	}
+
+	srcLen := userOptions.CopyListSize(src)
+	// The keys of the src items, nil if an item has no key.
+	srcKeys := make([]interface{}, srcLen)
+	srcSeen := make(map[interface{}]bool, srcLen)
+	for i := 0; i < srcLen; i++ {
+		itemKey, ok, err := listItemKey(src.Index(i), []int{keyField.index})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if srcSeen[itemKey] {
+			duplicates[itemKey] = true
+		}
+		srcSeen[itemKey] = true
+		srcKeys[i] = itemKey
+	}
+	for itemKey := range duplicates {
+		delete(dstItems, itemKey)
+	}
+
+	result := reflect.MakeSlice(dst.Type(), 0, srcLen)
+	for i := 0; i < srcLen; i++ {
+		srcItem := src.Index(i)
+		dstItem, found := dstItems[srcKeys[i]]
+		if !found {
+			dstItem = reflect.New(dst.Type().Elem()).Elem()
+		}
+
+		if err := structToStruct(itemsFilter, &srcItem, &dstItem, userOptions); err != nil {
+			return err
+		}
+		if srcKeys[i] != nil && !found {
+			// Keep the new item keyed even if the filter does not select the key field.
+			srcKey, dstKey := indirect(srcItem).Field(keyField.index), indirect(dstItem).FieldByIndex(keyField.dstIndex)
+			if err := structToStruct(Mask{}, &srcKey, &dstKey, userOptions); err != nil {
+				return err
+			}
+		}
+		result = reflect.Append(result, dstItem)
+	}
+	dst.Set(result)
+	return nil
+}
+
+// listItemKey returns the value of the key field of the given slice item by its index sequence. False is returned if the
+// item or the key is nil. An error is returned if the key (or the value of an interface key) is not comparable.
+func listItemKey(item reflect.Value, index []int) (interface{}, bool, error) {
+	item = indirect(item)
+	if !item.IsValid() {
+		return nil, false, nil
+	}
+	key := item.FieldByIndex(index)
+	if key.Kind() == reflect.Ptr || key.Kind() == reflect.Interface {
+		if key.IsNil() {
+			return nil, false, nil
+		}
+		// The dynamic type of an interface key must be comparable as well.
+		key = key.Elem()
+	}
+	if !key.Type().Comparable() {
+		return nil, false, errors.Errorf("list key field of type %s is not comparable", key.Type())
+	}
+	return key.Interface(), true, nil
+}
@@ -0,0 +1,372 @@
+package fieldmask_utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func listStrategyUsers() (src, dst *testproto.User) {
+	src = &testproto.User{
+		Images: []*testproto.Image{{ResizedUrl: "src1.jpg"}, {ResizedUrl: "src2.jpg"}},
+		Tags:   []string{"src"},
+	}
+	dst = &testproto.User{
+		Images: []*testproto.Image{
+			{OriginalUrl: "dst1.jpg", ResizedUrl: "dst1_resized.jpg"},
+			{OriginalUrl: "dst2.jpg"},
+			{OriginalUrl: "dst3.jpg"},
+		},
+		Tags: []string{"dst"},
+	}
+	return src, dst
+}
+
+func TestStructToStruct_WithListStrategy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []fieldmask_utils.Option
+		expected []*testproto.Image
+		tags     []string
+	}{
+		{
+			name: "merge by index by default",
+			expected: []*testproto.Image{
+				{OriginalUrl: "dst1.jpg", ResizedUrl: "src1.jpg"},
+				{OriginalUrl: "dst2.jpg", ResizedUrl: "src2.jpg"},
+			},
+			tags: []string{"src"},
+		},
+		{
+			name:     "replace",
+			opts:     []fieldmask_utils.Option{fieldmask_utils.WithListStrategy("", fieldmask_utils.Replace)},
+			expected: []*testproto.Image{{ResizedUrl: "src1.jpg"}, {ResizedUrl: "src2.jpg"}},
+			tags:     []string{"src"},
+		},
+		{
+			name: "append",
+			opts: []fieldmask_utils.Option{fieldmask_utils.WithListStrategy("", fieldmask_utils.Append)},
+			expected: []*testproto.Image{
+				{OriginalUrl: "dst1.jpg", ResizedUrl: "dst1_resized.jpg"},
+				{OriginalUrl: "dst2.jpg"},
+				{OriginalUrl: "dst3.jpg"},
+				{ResizedUrl: "src1.jpg"},
+				{ResizedUrl: "src2.jpg"},
+			},
+			tags: []string{"dst", "src"},
+		},
+		{
+			name: "path",
+			opts: []fieldmask_utils.Option{
+				fieldmask_utils.WithListStrategy("", fieldmask_utils.Replace),
+				fieldmask_utils.WithListStrategy("Tags", fieldmask_utils.Append),
+			},
+			expected: []*testproto.Image{{ResizedUrl: "src1.jpg"}, {ResizedUrl: "src2.jpg"}},
+			tags:     []string{"dst", "src"},
+		},
+		{
+			name: "last option wins",
+			opts: []fieldmask_utils.Option{
+				fieldmask_utils.WithListStrategy("Images", fieldmask_utils.Append),
+				fieldmask_utils.WithListStrategy("Images", fieldmask_utils.Replace),
+			},
+			expected: []*testproto.Image{{ResizedUrl: "src1.jpg"}, {ResizedUrl: "src2.jpg"}},
+			tags:     []string{"src"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src, dst := listStrategyUsers()
+			err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images{ResizedUrl},Tags"), src, dst,
+				tc.opts...)
+			require.NoError(t, err)
+			expected := &testproto.User{Images: tc.expected, Tags: tc.tags}
+			assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+		})
+	}
+}
+
+func TestStructToStruct_WithListStrategy_NilSrc(t *testing.T) {
+	_, dst := listStrategyUsers()
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images,Tags"), &testproto.User{}, dst,
+		fieldmask_utils.WithListStrategy("", fieldmask_utils.Append))
+	require.NoError(t, err)
+	assert.Len(t, dst.Images, 3)
+	assert.Equal(t, []string{"dst"}, dst.Tags)
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images,Tags"), &testproto.User{}, dst,
+		fieldmask_utils.WithListStrategy("", fieldmask_utils.Replace))
+	require.NoError(t, err)
+	assert.Nil(t, dst.Images)
+	assert.Nil(t, dst.Tags)
+}
+
+func TestStructToStruct_WithListStrategy_NestedPath(t *testing.T) {
+	type Group struct {
+		Items []int
+	}
+	type S struct {
+		Items  []int
+		Groups map[string]Group
+		Nested []struct {
+			Items []int
+		}
+	}
+	src := &S{
+		Items:  []int{1},
+		Groups: map[string]Group{"a": {Items: []int{1}}},
+		Nested: []struct{ Items []int }{{Items: []int{1}}},
+	}
+	dst := &S{
+		Items:  []int{2},
+		Groups: map[string]Group{"a": {Items: []int{2}}},
+		Nested: []struct{ Items []int }{{Items: []int{2}}},
+	}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items,Groups{a{Items}},Nested{Items}"), src,
+		dst, fieldmask_utils.WithListStrategy("Groups.*.Items", fieldmask_utils.Append),
+		fieldmask_utils.WithListStrategy("Nested.Items", fieldmask_utils.Append))
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, dst.Items)
+	assert.Equal(t, []int{2, 1}, dst.Groups["a"].Items)
+	assert.Equal(t, []int{2, 1}, dst.Nested[0].Items)
+}
+
+func TestStructToMap_WithListStrategy(t *testing.T) {
+	src, dstUser := listStrategyUsers()
+	dst := make(map[string]interface{})
+	require.NoError(t, fieldmask_utils.StructToMap(fieldmask_utils.Mask{}, dstUser, dst))
+
+	err := fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Images{ResizedUrl},Tags"), src, dst,
+		fieldmask_utils.WithListStrategy("Images", fieldmask_utils.Replace),
+		fieldmask_utils.WithListStrategy("Tags", fieldmask_utils.Append))
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"ResizedUrl": "src1.jpg"}, {"ResizedUrl": "src2.jpg"}}, dst["Images"])
+	assert.Equal(t, []string{"dst", "src"}, dst["Tags"])
+
+	err = fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Images{ResizedUrl}"), src, dst,
+		fieldmask_utils.WithListStrategy("", fieldmask_utils.Append))
+	require.NoError(t, err)
+	assert.Len(t, dst["Images"], 4)
+}
+
+func TestWithListStrategy_InvalidPath(t *testing.T) {
+	option := fieldmask_utils.WithListStrategy("Images..Items", fieldmask_utils.Append)
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images"), &testproto.User{},
+		&testproto.User{}, option)
+	assert.EqualError(t, err, `invalid list path "Images..Items": syntax error at offset 7: expected field name, got '.'`)
+
+	err = fieldmask_utils.StructToMap(fieldmask_utils.MaskFromString("Images"), &testproto.User{},
+		map[string]interface{}{}, option)
+	assert.Error(t, err)
+
+	_, err = fieldmask_utils.Compile(fieldmask_utils.MaskFromString("Images"), reflect.TypeOf(&testproto.User{}),
+		reflect.TypeOf(&testproto.User{}), option)
+	assert.Error(t, err)
+}
+
+func TestListStrategy_String(t *testing.T) {
+	assert.Equal(t, "MergeByIndex", fieldmask_utils.MergeByIndex.String())
+	assert.Equal(t, "Replace", fieldmask_utils.Replace.String())
+	assert.Equal(t, "Append", fieldmask_utils.Append.String())
+}
+
+func TestStructToStruct_WithListKey(t *testing.T) {
+	src := &testproto.User{
+		Images: []*testproto.Image{
+			{OriginalUrl: "b.jpg", ResizedUrl: "b_new.jpg"},
+			{OriginalUrl: "c.jpg", ResizedUrl: "c_new.jpg"},
+			{OriginalUrl: "a.jpg", ResizedUrl: "a_new.jpg"},
+		},
+	}
+	dstImageA := &testproto.Image{OriginalUrl: "a.jpg", ResizedUrl: "a.jpg"}
+	dst := &testproto.User{
+		Images: []*testproto.Image{
+			dstImageA,
+			{OriginalUrl: "d.jpg", ResizedUrl: "d.jpg"},
+			{OriginalUrl: "b.jpg", ResizedUrl: "b.jpg"},
+		},
+	}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images{ResizedUrl}"), src, dst,
+		fieldmask_utils.WithListKey("Images", "OriginalUrl"))
+	require.NoError(t, err)
+	expected := &testproto.User{
+		Images: []*testproto.Image{
+			{OriginalUrl: "b.jpg", ResizedUrl: "b_new.jpg"},
+			// The key is set for the new items even though the mask does not select it.
+			{OriginalUrl: "c.jpg", ResizedUrl: "c_new.jpg"},
+			{OriginalUrl: "a.jpg", ResizedUrl: "a_new.jpg"},
+		},
+	}
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+	// The matched items are updated in place.
+	assert.Same(t, dstImageA, dst.Images[2])
+}
+
+func TestStructToStruct_WithListKey_Nested(t *testing.T) {
+	src := &testproto.User{
+		Friends: []*testproto.User{{Images: []*testproto.Image{{OriginalUrl: "a.jpg", ResizedUrl: "a_new.jpg"}}}},
+	}
+	dst := &testproto.User{
+		Friends: []*testproto.User{{Images: []*testproto.Image{
+			{OriginalUrl: "b.jpg"},
+			{OriginalUrl: "a.jpg", ResizedUrl: "a.jpg"},
+		}}},
+	}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Friends{Images{ResizedUrl}}"), src, dst,
+		fieldmask_utils.WithListKey("Friends.Images", "OriginalUrl"))
+	require.NoError(t, err)
+	expected := &testproto.User{
+		Friends: []*testproto.User{{Images: []*testproto.Image{{OriginalUrl: "a.jpg", ResizedUrl: "a_new.jpg"}}}},
+	}
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+}
+
+type listKeyItem struct {
+	ID    *int
+	Name  string
+	Value int
+}
+
+type listKeyContainer struct {
+	Items    []listKeyItem `fieldmask:"key=ID"`
+	Untagged []listKeyItem
+}
+
+func TestStructToStruct_WithListKeyTag(t *testing.T) {
+	id := func(i int) *int { return &i }
+	src := &listKeyContainer{
+		Items: []listKeyItem{
+			{ID: id(2), Value: 20},
+			{Value: 30},
+			{ID: id(1), Value: 10},
+			{ID: id(1), Value: 11},
+		},
+		Untagged: []listKeyItem{{ID: id(2), Value: 20}},
+	}
+	dst := &listKeyContainer{
+		Items: []listKeyItem{
+			{ID: id(1), Name: "one"},
+			{Name: "none"},
+			{ID: id(2), Name: "two"},
+			{ID: id(3), Name: "three"},
+		},
+		Untagged: []listKeyItem{{ID: id(1), Name: "one"}},
+	}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value},Untagged{Value}"), src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []listKeyItem{
+		{ID: id(2), Name: "two", Value: 20},
+		// The items without a key (or with a duplicate one) are never matched.
+		{Value: 30},
+		{ID: id(1), Value: 10},
+		{ID: id(1), Value: 11},
+	}, dst.Items)
+	assert.Equal(t, []listKeyItem{{ID: id(1), Name: "one", Value: 20}}, dst.Untagged)
+
+	// The options win over the tag.
+	dst = &listKeyContainer{Items: []listKeyItem{{ID: id(1), Name: "one"}}}
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value}"), src, dst,
+		fieldmask_utils.WithListStrategy("Items", fieldmask_utils.MergeByIndex))
+	require.NoError(t, err)
+	assert.Equal(t, []listKeyItem{{ID: id(1), Name: "one", Value: 20}, {Value: 30}, {Value: 10}, {Value: 11}},
+		dst.Items)
+}
+
+func TestStructToStruct_WithListKeyTag_DuplicateDstKeys(t *testing.T) {
+	id := func(i int) *int { return &i }
+	src := &listKeyContainer{Items: []listKeyItem{{ID: id(1), Value: 10}, {ID: id(2), Value: 20}}}
+	dst := &listKeyContainer{Items: []listKeyItem{
+		{ID: id(1), Name: "one"},
+		{ID: id(1), Name: "another one"},
+		{ID: id(2), Name: "two"},
+	}}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value}"), src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []listKeyItem{{ID: id(1), Value: 10}, {ID: id(2), Name: "two", Value: 20}}, dst.Items)
+}
+
+type listKeyPtrContainer struct {
+	Items *[]listKeyItem `fieldmask:"key=ID"`
+}
+
+func TestStructToStruct_WithListKeyTag_SlicePtr(t *testing.T) {
+	id := func(i int) *int { return &i }
+	src := &listKeyPtrContainer{Items: &[]listKeyItem{{ID: id(2), Value: 20}, {ID: id(1), Value: 10}}}
+	dst := &listKeyPtrContainer{Items: &[]listKeyItem{{ID: id(1), Name: "one"}, {ID: id(2), Name: "two"}}}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value}"), src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []listKeyItem{{ID: id(2), Name: "two", Value: 20}, {ID: id(1), Name: "one", Value: 10}},
+		*dst.Items)
+}
+
+type listInterfaceKeyItem struct {
+	ID    interface{}
+	Value int
+}
+
+func TestStructToStruct_WithListKey_InterfaceKey(t *testing.T) {
+	src := []listInterfaceKeyItem{{ID: "b", Value: 2}, {ID: nil, Value: 3}}
+	dstItems := &struct{ Items []listInterfaceKeyItem }{Items: []listInterfaceKeyItem{{ID: "a"}, {ID: "b"}}}
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value}"),
+		&struct{ Items []listInterfaceKeyItem }{Items: src}, dstItems, fieldmask_utils.WithListKey("Items", "ID"))
+	require.NoError(t, err)
+	assert.Equal(t, []listInterfaceKeyItem{{ID: "b", Value: 2}, {Value: 3}}, dstItems.Items)
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Items{Value}"),
+		&struct{ Items []listInterfaceKeyItem }{Items: []listInterfaceKeyItem{{ID: []int{1}}}}, dstItems,
+		fieldmask_utils.WithListKey("Items", "ID"))
+	assert.EqualError(t, err, "list key field of type []int is not comparable")
+}
+
+func TestStructToStruct_WithListKey_Errors(t *testing.T) {
+	err := fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images"), &testproto.User{
+		Images: []*testproto.Image{{}},
+	}, &testproto.User{}, fieldmask_utils.WithListKey("Images", "Unknown"))
+	assert.Error(t, err)
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Tags"), &testproto.User{Tags: []string{"a"}},
+		&testproto.User{}, fieldmask_utils.WithListKey("Tags", "Name"))
+	assert.Error(t, err)
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images"), &testproto.User{},
+		&testproto.User{}, fieldmask_utils.WithListKey("Images", ""))
+	assert.EqualError(t, err, `list key for path "Images" must not be empty`)
+
+	err = fieldmask_utils.StructToStruct(fieldmask_utils.MaskFromString("Images"), &testproto.User{},
+		&testproto.User{}, fieldmask_utils.WithListKey("Images.`", "OriginalUrl"))
+	assert.Error(t, err)
+}
+
+func TestStructToStruct_WithListKey_SamePathRules(t *testing.T) {
+	src := &testproto.User{Images: []*testproto.Image{
+		{OriginalUrl: "b", ResizedUrl: "b2"},
+		{OriginalUrl: "a", ResizedUrl: "a2"},
+	}}
+	mask := fieldmask_utils.MaskFromString("Images{ResizedUrl}")
+
+	// The rules for the same path are merged: the key is kept by a later MergeByIndex strategy.
+	dst := &testproto.User{Images: []*testproto.Image{{OriginalUrl: "a", ResizedUrl: "a1"}}}
+	err := fieldmask_utils.StructToStruct(mask, src, dst, fieldmask_utils.WithListKey("Images", "OriginalUrl"),
+		fieldmask_utils.WithListStrategy("Images", fieldmask_utils.MergeByIndex))
+	require.NoError(t, err)
+	assert.Equal(t, []*testproto.Image{
+		{OriginalUrl: "b", ResizedUrl: "b2"},
+		{OriginalUrl: "a", ResizedUrl: "a2"},
+	}, dst.Images)
+
+	err = fieldmask_utils.StructToStruct(mask, src, &testproto.User{},
+		fieldmask_utils.WithListKey("Images", "OriginalUrl"),
+		fieldmask_utils.WithListStrategy("Images", fieldmask_utils.Append))
+	assert.EqualError(t, err, `list strategy Append conflicts with list key OriginalUrl for path "Images"`)
+
+	err = fieldmask_utils.StructToStruct(mask, src, &testproto.User{},
+		fieldmask_utils.WithListStrategy("Images", fieldmask_utils.Replace),
+		fieldmask_utils.WithListKey("Images", "OriginalUrl"))
+	assert.EqualError(t, err, `list key OriginalUrl conflicts with list strategy Replace for path "Images"`)
+}
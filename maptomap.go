@@ -0,0 +1,110 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// MapToMap copies the `src` map to the `dst` map using the given FieldFilter without requiring any Go struct types,
+// e.g. to filter an arbitrary decoded JSON document. The nested map[string]interface{} values are treated as structs:
+// the filter selects their keys and the selected entries are copied recursively. The sub-filter of a []interface{}
+// value is applied to every item of the slice (the nested slices are transparent), the same way it is applied to the
+// items of a repeated field. The values of the other types, as well as the values selected with an empty filter, are
+// copied as is.
+// The selected entries missing in `src` are deleted from `dst`, the same way the selected keys of a Go map field are
+// (see StructToStruct). The selected maps are merged into the existing `dst` maps and the slices are merged by index
+// (see WithListStrategy).
+// Unless the WithDeepCopy option is set `dst` shares the values copied as is with `src`.
+func MapToMap(filter FieldFilter, src, dst map[string]interface{}, userOpts ...Option) error {
+	opts := newDefaultOptions()
+	for _, o := range userOpts {
+		o(opts)
+	}
+	if opts.listRulesErr != nil {
+		return opts.listRulesErr
+	}
+	if dst == nil {
+		return errors.New("dst must not be nil")
+	}
+	untypedMapToMap(filter, src, dst, opts)
+	return checkStrictPaths(filter, opts)
+}
+
+// untypedMapToMap copies the entries of the `src` map selected by the filter to the `dst` map, see MapToMap.
+func untypedMapToMap(filter FieldFilter, src, dst map[string]interface{}, userOptions *options) {
+	if userOptions.pathTracker != nil {
+		keys := make([]string, 0, len(src))
+		for key := range src {
+			keys = append(keys, key)
+		}
+		userOptions.pathTracker.visit(filter, keys, nil)
+	}
+	for key := range dst {
+		if _, ok := filter.Filter(key); ok {
+			if _, ok := src[key]; !ok {
+				delete(dst, key)
+			}
+		}
+	}
+	for key, value := range src {
+		subFilter, ok := filter.Filter(key)
+		if !ok {
+			continue
+		}
+		userOptions.pushPath(key)
+		dst[key] = untypedValue(subFilter, value, dst[key], userOptions)
+		userOptions.popPath()
+	}
+}
+
+// untypedValue returns the `src` value of an untyped map copied using the filter to the existing `dst` value.
+func untypedValue(filter FieldFilter, src, dst interface{}, userOptions *options) interface{} {
+	if filter.IsEmpty() {
+		return untypedCopy(src, userOptions)
+	}
+	switch src := src.(type) {
+	case map[string]interface{}:
+		dstMap, ok := dst.(map[string]interface{})
+		if !ok || dstMap == nil {
+			dstMap = make(map[string]interface{})
+		}
+		untypedMapToMap(filter, src, dstMap, userOptions)
+		return dstMap
+
+	case []interface{}:
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			return dst
+		}
+		srcValue := reflect.ValueOf(src)
+		srcLen := userOptions.CopyListSize(&srcValue)
+		dstItems, _ := dst.([]interface{})
+		items := make([]interface{}, 0, srcLen)
+		switch strategy, _ := userOptions.listStrategy(""); strategy {
+		case Replace:
+			dstItems = nil
+		case Append:
+			// The existing items are kept, the src items are copied to the new items.
+			items = append(make([]interface{}, 0, len(dstItems)+srcLen), dstItems...)
+			dstItems = nil
+		}
+		for i := 0; i < srcLen; i++ {
+			var dstItem interface{}
+			if i < len(dstItems) {
+				dstItem = dstItems[i]
+			}
+			items = append(items, untypedValue(itemsFilter, src[i], dstItem, userOptions))
+		}
+		return items
+	}
+	return untypedCopy(src, userOptions)
+}
+
+// untypedCopy returns the given value of an untyped map as is or its deep copy if the DeepCopy option is set.
+func untypedCopy(v interface{}, userOptions *options) interface{} {
+	if !userOptions.DeepCopy || v == nil {
+		return v
+	}
+	return deepCopy(reflect.ValueOf(v)).Interface()
+}
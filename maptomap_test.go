@@ -0,0 +1,153 @@
+package fieldmask_utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+)
+
+const mapToMapDocument = `{
+	"id": 1,
+	"name": "John",
+	"address": {"city": "Paris", "zip": "75001"},
+	"orders": [
+		{"id": 1, "items": [{"sku": "a", "qty": 1}], "total": 10},
+		{"id": 2, "items": [{"sku": "b", "qty": 2}, {"sku": "c", "qty": 3}], "total": 20}
+	],
+	"matrix": [[{"x": 1, "y": 2}], [{"x": 3, "y": 4}]],
+	"tags": ["a", "b"]
+}`
+
+func TestMapToMap(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   fieldmask_utils.FieldFilter
+		expected string
+	}{
+		{
+			name:     "empty mask",
+			filter:   fieldmask_utils.Mask{},
+			expected: mapToMapDocument,
+		},
+		{
+			name:     "top level keys",
+			filter:   fieldmask_utils.MaskFromString("id,tags,unknown"),
+			expected: `{"id": 1, "tags": ["a", "b"]}`,
+		},
+		{
+			name:     "nested object",
+			filter:   fieldmask_utils.MaskFromString("address{city}"),
+			expected: `{"address": {"city": "Paris"}}`,
+		},
+		{
+			name:   "arrays",
+			filter: fieldmask_utils.MaskFromString("orders{id,items{sku}},matrix{x}"),
+			expected: `{
+				"orders": [{"id": 1, "items": [{"sku": "a"}]}, {"id": 2, "items": [{"sku": "b"}, {"sku": "c"}]}],
+				"matrix": [[{"x": 1}], [{"x": 3}]]
+			}`,
+		},
+		{
+			name: "inverse",
+			filter: fieldmask_utils.MaskInverse{
+				"address": nil,
+				"orders":  fieldmask_utils.MaskInverse{"items": nil},
+				"matrix":  nil,
+			},
+			expected: `{
+				"id": 1,
+				"name": "John",
+				"orders": [{"id": 1, "total": 10}, {"id": 2, "total": 20}],
+				"tags": ["a", "b"]
+			}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make(map[string]interface{})
+			err := fieldmask_utils.MapToMap(tc.filter, decodeJSONMap(t, mapToMapDocument), dst)
+			require.NoError(t, err)
+			assert.Equal(t, decodeJSONMap(t, tc.expected), dst)
+		})
+	}
+}
+
+func TestMapToMap_Merge(t *testing.T) {
+	dst := decodeJSONMap(t, `{
+		"name": "Jane",
+		"address": {"city": "Lyon", "country": "France"},
+		"orders": [{"id": 3, "status": "new"}, {"id": 4}, {"id": 5}]
+	}`)
+	err := fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("address{city},orders{id}"),
+		decodeJSONMap(t, mapToMapDocument), dst)
+	require.NoError(t, err)
+	assert.Equal(t, decodeJSONMap(t, `{
+		"name": "Jane",
+		"address": {"city": "Paris", "country": "France"},
+		"orders": [{"id": 1, "status": "new"}, {"id": 2}]
+	}`), dst)
+
+	err = fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("orders{id}"), decodeJSONMap(t, mapToMapDocument),
+		dst, fieldmask_utils.WithListStrategy("orders", fieldmask_utils.Append))
+	require.NoError(t, err)
+	expected := decodeJSONMap(t, `{"orders": [{"id": 1, "status": "new"}, {"id": 2}, {"id": 1}, {"id": 2}]}`)
+	assert.Equal(t, expected["orders"], dst["orders"])
+}
+
+func TestMapToMap_MissingInSrc(t *testing.T) {
+	dst := decodeJSONMap(t, `{
+		"name": "Jane",
+		"email": "jane@example.com",
+		"address": {"city": "Lyon", "country": "France", "zip": "69001"}
+	}`)
+	// The selected entries missing in src are deleted, the same as the selected keys of a Go map field.
+	err := fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("id,email,address{city,country}"),
+		decodeJSONMap(t, mapToMapDocument), dst)
+	require.NoError(t, err)
+	assert.Equal(t, decodeJSONMap(t, `{
+		"id": 1,
+		"name": "Jane",
+		"address": {"city": "Paris", "zip": "69001"}
+	}`), dst)
+}
+
+func TestMapToMap_DeepCopy(t *testing.T) {
+	src := decodeJSONMap(t, mapToMapDocument)
+	dst := make(map[string]interface{})
+	require.NoError(t, fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("address,tags"), src, dst))
+	src["address"].(map[string]interface{})["city"] = "changed"
+	assert.Equal(t, "changed", dst["address"].(map[string]interface{})["city"])
+
+	src = decodeJSONMap(t, mapToMapDocument)
+	dst = make(map[string]interface{})
+	require.NoError(t, fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("address,tags"), src, dst,
+		fieldmask_utils.WithDeepCopy()))
+	src["address"].(map[string]interface{})["city"] = "changed"
+	src["tags"].([]interface{})[0] = "changed"
+	assert.Equal(t, decodeJSONMap(t, `{"address": {"city": "Paris", "zip": "75001"}, "tags": ["a", "b"]}`), dst)
+}
+
+func TestMapToMap_StrictPaths(t *testing.T) {
+	err := fieldmask_utils.MapToMap(fieldmask_utils.MaskFromString("id,orders{items{sku,price}},unknown"),
+		decodeJSONMap(t, mapToMapDocument), make(map[string]interface{}), fieldmask_utils.WithStrictPaths())
+	var unknownPathsErr *fieldmask_utils.UnknownPathsError
+	require.ErrorAs(t, err, &unknownPathsErr)
+	assert.Equal(t, []string{"orders.items.price", "unknown"}, unknownPathsErr.Paths)
+}
+
+func TestMapToMap_NilDst(t *testing.T) {
+	assert.Error(t, fieldmask_utils.MapToMap(fieldmask_utils.Mask{}, map[string]interface{}{}, nil))
+}
+
+func TestMapToStruct_UntypedInterface(t *testing.T) {
+	dst := &mapToStructPerson{}
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.MaskFromString("extra{foo,bar{baz}}"),
+		decodeJSONMap(t, `{"extra": {"foo": 1, "bar": [{"baz": 2, "qux": 3}], "ignored": 4}}`), dst,
+		fieldmask_utils.WithTag("json"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": 1.0, "bar": []interface{}{map[string]interface{}{"baz": 2.0}}},
+		dst.Extra)
+}
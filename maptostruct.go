@@ -0,0 +1,279 @@
+package fieldmask_utils
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// MapToStruct copies the `src` map (e.g. a decoded JSON object) to the `dst` struct using the given FieldFilter.
+// It is the reverse of StructToMap: the filter selects the map keys, which are matched against the dst field names
+// according to the SrcTag option (or the DstTag one if SrcTag is not set, so that WithTag("json") can be used as
+// well). The selected fields are overwritten with the corresponding map values, the fields missing in `src` are left
+// intact and a nil value sets the field to its zero value.
+// The values are copied recursively: the nested maps are copied to structs (or pointers to them) and maps, the slices
+// are copied item by item (the existing dst items are reused), the numbers are converted to the numeric type of the
+// field (float64 JSON numbers must fit into it without losing precision) and the values of the other types are
+// assigned or converted to the field type if they are of the same kind. The converter hooks (see WithConverterHook)
+// are run for the values of the other kinds, their result is copied if it is assignable to the field.
+// The maps and slices stored to interface fields are copied the same way MapToMap copies them, other values are
+// assigned as is. The protobuf oneof members are not set.
+func MapToStruct(filter FieldFilter, src map[string]interface{}, dst interface{}, userOpts ...Option) error {
+	opts := newDefaultOptions()
+	for _, o := range userOpts {
+		o(opts)
+	}
+	if opts.listRulesErr != nil {
+		return opts.listRulesErr
+	}
+	if opts.SrcTag == "" {
+		opts.SrcTag = opts.DstTag
+	}
+
+	dstVal := reflect.ValueOf(dst)
+	if dstVal.Kind() != reflect.Ptr {
+		return errors.Errorf("dst must be a pointer, %s given", dstVal.Kind())
+	}
+	dstVal = indirect(dstVal)
+	if dstVal.Kind() != reflect.Struct {
+		return errors.Errorf("dst kind must be a struct, %s given", dstVal.Kind())
+	}
+	if err := mapToStruct(filter, reflect.ValueOf(src), dstVal, opts); err != nil {
+		return err
+	}
+	return checkStrictPaths(filter, opts)
+}
+
+// mapToStruct copies the `src` map with string keys to the `dst` struct.
+func mapToStruct(filter FieldFilter, src, dst reflect.Value, userOptions *options) error {
+	if userOptions.pathTracker != nil {
+		userOptions.pathTracker.visitStruct(filter, dst.Type(), userOptions)
+	}
+	for _, f := range structCopyFields(dst.Type(), dst.Type(), userOptions) {
+		if f.oneof || !isExported(dst.Type().Field(f.index)) {
+			continue
+		}
+		subFilter, ok := filter.Filter(f.srcName)
+		if !ok {
+			continue
+		}
+		value := src.MapIndex(reflect.ValueOf(f.srcName).Convert(src.Type().Key()))
+		if !value.IsValid() {
+			continue
+		}
+		if err := mapValueToValue(subFilter, value, dst.Field(f.index), userOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapValueToValue copies the `src` value of a map to the `dst` value, see MapToStruct.
+func mapValueToValue(filter FieldFilter, src, dst reflect.Value, userOptions *options) error {
+	for src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if !src.IsValid() || src.Kind() == reflect.Interface {
+		// A nil value.
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if !mapValueCompatible(src.Type(), dst.Type()) {
+		for _, fn := range userOptions.ConverterHooks {
+			data, err := fn(&src, &dst)
+			if err != nil {
+				return err
+			}
+			converted := reflect.ValueOf(data)
+			if !converted.IsValid() || !converted.Type().AssignableTo(dst.Type()) {
+				// No change using conversion, try next.
+				continue
+			}
+			dst.Set(converted)
+			return nil
+		}
+		return errors.Errorf("map value of type %s can't be copied to %s", src.Type(), dst.Type())
+	}
+
+	if dst.Kind() == reflect.Ptr && !src.Type().AssignableTo(dst.Type()) {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return mapValueToValue(filter, src, dst.Elem(), userOptions)
+	}
+
+	switch {
+	case dst.Kind() == reflect.Interface:
+		if !filter.IsEmpty() && isUntyped(src.Type()) {
+			if result := untypedValue(filter, src.Interface(), dst.Interface(), userOptions); result != nil {
+				dst.Set(reflect.ValueOf(result))
+			}
+			break
+		}
+		// The value is stored as is (or its deep copy), whatever its type is.
+		dst.Set(reflect.ValueOf(untypedCopy(src.Interface(), userOptions)))
+
+	case src.Kind() == reflect.Map && dst.Kind() == reflect.Struct:
+		return mapToStruct(filter, src, dst, userOptions)
+
+	case src.Kind() == reflect.Map && dst.Kind() == reflect.Map:
+		if src.Type() == dst.Type() {
+			return mapToMap(filter, &src, &dst, userOptions)
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			key := mapKeyString(iter.Key())
+			subFilter, ok := mapKeyFilter(filter, key)
+			if !ok {
+				continue
+			}
+			dstKey, err := convertMapKey(iter.Key(), dst.Type().Key())
+			if err != nil {
+				return err
+			}
+			dstValue := reflect.New(dst.Type().Elem()).Elem()
+			if existing := dst.MapIndex(dstKey); existing.IsValid() {
+				dstValue.Set(existing)
+			}
+			if err := mapValueToValue(subFilter, iter.Value(), dstValue, userOptions); err != nil {
+				return err
+			}
+			dst.SetMapIndex(dstKey, dstValue)
+		}
+
+	case src.Kind() == reflect.Slice && src.Type() != dst.Type():
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			return nil
+		}
+		srcLen := userOptions.CopyListSize(&src)
+		if dst.Kind() == reflect.Array {
+			if dst.Len() < srcLen {
+				return errors.Errorf("dst array size %d is less than src size %d", dst.Len(), srcLen)
+			}
+			for i := 0; i < srcLen; i++ {
+				if err := mapValueToValue(itemsFilter, src.Index(i), dst.Index(i), userOptions); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		dstLen := dst.Len()
+		if dstLen > srcLen {
+			dstLen = srcLen
+		}
+		items := reflect.MakeSlice(dst.Type(), srcLen, srcLen)
+		reflect.Copy(items, dst.Slice(0, dstLen))
+		for i := 0; i < srcLen; i++ {
+			if err := mapValueToValue(itemsFilter, src.Index(i), items.Index(i), userOptions); err != nil {
+				return err
+			}
+		}
+		dst.Set(items)
+
+	case isNumber(src.Kind()) && isNumber(dst.Kind()) && src.Kind() != dst.Kind():
+		return convertNumber(src, dst)
+
+	case src.Type().AssignableTo(dst.Type()):
+		if userOptions.DeepCopy {
+			dst.Set(deepCopy(src))
+		} else {
+			dst.Set(src)
+		}
+
+	default:
+		dst.Set(src.Convert(dst.Type()))
+	}
+	return nil
+}
+
+// mapValueCompatible returns true if a map value of the src type can be copied to the dst type without the converter
+// hooks, see mapValueToValue.
+func mapValueCompatible(src, dst reflect.Type) bool {
+	if dst.Kind() == reflect.Ptr && !src.AssignableTo(dst) {
+		dst = dst.Elem()
+	}
+	switch {
+	case src.AssignableTo(dst):
+		return true
+	case src.Kind() == reflect.Map:
+		return dst.Kind() == reflect.Map || dst.Kind() == reflect.Struct && src.Key().Kind() == reflect.String
+	case src.Kind() == reflect.Slice:
+		return dst.Kind() == reflect.Slice || dst.Kind() == reflect.Array
+	case isNumber(src.Kind()):
+		return isNumber(dst.Kind())
+	}
+	return src.Kind() == dst.Kind() && src.ConvertibleTo(dst)
+}
+
+// isUntyped returns true for the map and slice types MapToMap copies, i.e. the JSON objects and arrays.
+func isUntyped(t reflect.Type) bool {
+	return t == reflect.TypeOf(map[string]interface{}{}) || t == reflect.TypeOf([]interface{}{})
+}
+
+// isNumber returns true for the integer and floating point kinds.
+func isNumber(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64
+}
+
+// convertNumber sets the `src` number to the `dst` value of another numeric kind. An error is returned if the number
+// does not fit into `dst`: e.g. if it has a fractional part and `dst` is an integer.
+func convertNumber(src, dst reflect.Value) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch src.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return errors.Errorf("%v is not a valid %s", f, dst.Type())
+			}
+			n = int64(f)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if src.Uint() > math.MaxInt64 {
+				return errors.Errorf("%v overflows %s", src.Uint(), dst.Type())
+			}
+			n = int64(src.Uint())
+		default:
+			n = src.Int()
+		}
+		if dst.OverflowInt(n) {
+			return errors.Errorf("%v overflows %s", n, dst.Type())
+		}
+		dst.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch src.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f := src.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return errors.Errorf("%v is not a valid %s", f, dst.Type())
+			}
+			n = uint64(f)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if src.Int() < 0 {
+				return errors.Errorf("%v is not a valid %s", src.Int(), dst.Type())
+			}
+			n = uint64(src.Int())
+		default:
+			n = src.Uint()
+		}
+		if dst.OverflowUint(n) {
+			return errors.Errorf("%v overflows %s", n, dst.Type())
+		}
+		dst.SetUint(n)
+
+	default:
+		f := src.Convert(reflect.TypeOf(float64(0))).Float()
+		if dst.OverflowFloat(f) {
+			return errors.Errorf("%v overflows %s", f, dst.Type())
+		}
+		dst.SetFloat(f)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package fieldmask_utils_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func decodeJSONMap(t *testing.T, s string) map[string]interface{} {
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+func TestMapToStruct_Proto(t *testing.T) {
+	src := decodeJSONMap(t, `{
+		"Id": 1,
+		"Username": "new",
+		"Role": 2,
+		"Avatar": {"OriginalUrl": "new.jpg", "ResizedUrl": "ignored.jpg"},
+		"Images": [{"OriginalUrl": "1.jpg"}, {"OriginalUrl": "2.jpg"}],
+		"Tags": ["a", "b"],
+		"Permissions": [1, 2],
+		"Meta": {"foo": "bar"}
+	}`)
+	dst := &testproto.User{
+		Username:    "old",
+		Deactivated: true,
+		Avatar:      &testproto.Image{OriginalUrl: "old.jpg", ResizedUrl: "old_resized.jpg"},
+		Images:      []*testproto.Image{{ResizedUrl: "1_resized.jpg"}},
+		Meta:        map[string]string{"baz": "qux"},
+	}
+	mask := fieldmask_utils.MaskFromString("Id,Username,Deactivated,Role,Avatar{OriginalUrl},Images,Tags,Permissions,Meta")
+	err := fieldmask_utils.MapToStruct(mask, src, dst)
+	require.NoError(t, err)
+	expected := &testproto.User{
+		Id:          1,
+		Username:    "new",
+		Deactivated: true,
+		Role:        testproto.Role_ADMIN,
+		Avatar:      &testproto.Image{OriginalUrl: "new.jpg", ResizedUrl: "old_resized.jpg"},
+		Images:      []*testproto.Image{{OriginalUrl: "1.jpg", ResizedUrl: "1_resized.jpg"}, {OriginalUrl: "2.jpg"}},
+		Tags:        []string{"a", "b"},
+		Permissions: []testproto.Permission{testproto.Permission_WRITE, testproto.Permission_EXECUTE},
+		Meta:        map[string]string{"foo": "bar", "baz": "qux"},
+	}
+	assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+}
+
+func TestMapToStruct_Interface(t *testing.T) {
+	dst := &struct{ Extra, Doc interface{} }{}
+	src := decodeJSONMap(t, `{"Extra": [1, "x"], "Doc": {"a": [{"b": 1}]}}`)
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1.0, "x"}, dst.Extra)
+	assert.Equal(t, map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": 1.0}}}, dst.Doc)
+
+	err = fieldmask_utils.MapToStruct(fieldmask_utils.MaskFromString("Extra"), src, dst, fieldmask_utils.WithDeepCopy())
+	require.NoError(t, err)
+	src["Extra"].([]interface{})[0] = 2.0
+	assert.Equal(t, []interface{}{1.0, "x"}, dst.Extra)
+}
+
+func TestMapToStruct_Null(t *testing.T) {
+	dst := &testproto.User{Avatar: &testproto.Image{OriginalUrl: "old.jpg"}, Tags: []string{"a"}}
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, decodeJSONMap(t, `{"Avatar": null, "Tags": null}`),
+		dst)
+	require.NoError(t, err)
+	assert.Nil(t, dst.Avatar)
+	assert.Nil(t, dst.Tags)
+}
+
+type mapToStructAddress struct {
+	City string `json:"city"`
+	Zip  *int   `json:"zip"`
+}
+
+type mapToStructPerson struct {
+	Name      string                        `json:"name"`
+	Age       uint8                         `json:"age"`
+	Score     float32                       `json:"score"`
+	Address   *mapToStructAddress           `json:"address"`
+	Addresses map[string]mapToStructAddress `json:"addresses"`
+	Codes     [2]int                        `json:"codes"`
+	Extra     interface{}                   `json:"extra"`
+	Born      time.Time                     `json:"born"`
+}
+
+func TestMapToStruct_Tags(t *testing.T) {
+	src := decodeJSONMap(t, `{
+		"name": "John",
+		"age": 42,
+		"score": 0.5,
+		"address": {"city": "Paris", "zip": 75001},
+		"addresses": {"home": {"city": "Lyon"}},
+		"codes": [1, 2],
+		"extra": {"foo": [1]}
+	}`)
+	dst := &mapToStructPerson{}
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, src, dst, fieldmask_utils.WithTag("json"))
+	require.NoError(t, err)
+	zip := 75001
+	assert.Equal(t, &mapToStructPerson{
+		Name:      "John",
+		Age:       42,
+		Score:     0.5,
+		Address:   &mapToStructAddress{City: "Paris", Zip: &zip},
+		Addresses: map[string]mapToStructAddress{"home": {City: "Lyon"}},
+		Codes:     [2]int{1, 2},
+		Extra:     map[string]interface{}{"foo": []interface{}{1.0}},
+	}, dst)
+
+	dst = &mapToStructPerson{}
+	err = fieldmask_utils.MapToStruct(fieldmask_utils.MaskFromString("address{zip}"), src, dst,
+		fieldmask_utils.WithSrcTag("json"))
+	require.NoError(t, err)
+	assert.Equal(t, &mapToStructPerson{Address: &mapToStructAddress{Zip: &zip}}, dst)
+}
+
+func TestMapToStruct_InvalidNumbers(t *testing.T) {
+	testCases := []string{
+		`{"age": 1.5}`,
+		`{"age": 256}`,
+		`{"age": -1}`,
+		`{"address": {"zip": 1e100}}`,
+	}
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			err := fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, decodeJSONMap(t, tc), &mapToStructPerson{},
+				fieldmask_utils.WithTag("json"))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMapToStruct_ConverterHook(t *testing.T) {
+	src := decodeJSONMap(t, `{"born": "2020-01-02T03:04:05Z", "name": 42}`)
+	dst := &mapToStructPerson{}
+
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.MaskFromString("born"), src, dst, fieldmask_utils.WithTag("json"))
+	assert.Error(t, err)
+
+	parseTime := func(src, dst *reflect.Value) (interface{}, error) {
+		if dst.Type() != reflect.TypeOf(time.Time{}) {
+			return nil, nil
+		}
+		return time.Parse(time.RFC3339, src.String())
+	}
+	formatNumber := func(src, dst *reflect.Value) (interface{}, error) {
+		if dst.Kind() != reflect.String {
+			return nil, nil
+		}
+		return strconv.FormatFloat(src.Float(), 'f', -1, 64), nil
+	}
+	err = fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, src, dst, fieldmask_utils.WithTag("json"),
+		fieldmask_utils.WithConverterHook(parseTime), fieldmask_utils.WithConverterHook(formatNumber))
+	require.NoError(t, err)
+	assert.Equal(t, &mapToStructPerson{Name: "42", Born: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}, dst)
+}
+
+func TestMapToStruct_StrictPaths(t *testing.T) {
+	err := fieldmask_utils.MapToStruct(fieldmask_utils.MaskFromString("name,address{city,street},unknown"),
+		decodeJSONMap(t, `{"name": "John", "address": {"city": "Paris"}}`), &mapToStructPerson{},
+		fieldmask_utils.WithTag("json"), fieldmask_utils.WithStrictPaths())
+	var unknownPathsErr *fieldmask_utils.UnknownPathsError
+	require.ErrorAs(t, err, &unknownPathsErr)
+	assert.Equal(t, []string{"address.street", "unknown"}, unknownPathsErr.Paths)
+}
+
+func TestMapToStruct_InvalidDst(t *testing.T) {
+	assert.Error(t, fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, nil, mapToStructPerson{}))
+	var s string
+	assert.Error(t, fieldmask_utils.MapToStruct(fieldmask_utils.Mask{}, nil, &s))
+}
@@ -0,0 +1,278 @@
+package fieldmask_utils
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The text representation of a mask is its sorted paths joined with commas like "a.b,a.c,d", which is also the way
+// the proto3 JSON mapping represents a google.protobuf.FieldMask (field names are used as is though, they are not
+// converted to lowerCamelCase). Mask and MaskInverse keep the default JSON encoding of their maps (a nested object like
+// {"a":{"b":{},"c":{}},"d":{}}): the MaskText (MaskInverseText) type marshals a mask as text and to JSON as a string
+// with its text representation, the MaskObject (MaskInverseObject) type marshals it as a normalized nested object.
+// Both of the JSON forms are accepted when unmarshaling. The text may also be in the format of ParseMask, e.g.
+// "a{b,c},d" which is detected by the presence of curly braces.
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *Mask) UnmarshalText(text []byte) error {
+	mask, err := filterFromText(string(text), func() FieldFilterContainer { return make(Mask) })
+	if err != nil {
+		return err
+	}
+	*m = mask.(Mask)
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *Mask) UnmarshalJSON(data []byte) error {
+	mask, err := filterFromJSON(data, func() FieldFilterContainer { return make(Mask) })
+	if err != nil || mask == nil {
+		return err
+	}
+	*m = mask.(Mask)
+	return nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *MaskInverse) UnmarshalText(text []byte) error {
+	mask, err := filterFromText(string(text), func() FieldFilterContainer { return make(MaskInverse) })
+	if err != nil {
+		return err
+	}
+	*m = mask.(MaskInverse)
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MaskInverse) UnmarshalJSON(data []byte) error {
+	mask, err := filterFromJSON(data, func() FieldFilterContainer { return make(MaskInverse) })
+	if err != nil || mask == nil {
+		return err
+	}
+	*m = mask.(MaskInverse)
+	return nil
+}
+
+// MaskText is a Mask which is marshaled as text (and to JSON as a string) with its comma separated paths like "a.b,c".
+type MaskText Mask
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m MaskText) MarshalText() ([]byte, error) {
+	paths, err := Mask(m).Paths(nil)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(paths, ",")), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *MaskText) UnmarshalText(text []byte) error {
+	return (*Mask)(m).UnmarshalText(text)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m MaskText) MarshalJSON() ([]byte, error) {
+	return textToJSON(m.MarshalText())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MaskText) UnmarshalJSON(data []byte) error {
+	return (*Mask)(m).UnmarshalJSON(data)
+}
+
+// MaskInverseText is a MaskInverse which is marshaled as text (and to JSON as a string) with its comma separated paths
+// like "a.b,c".
+type MaskInverseText MaskInverse
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (m MaskInverseText) MarshalText() ([]byte, error) {
+	return MaskText(m).MarshalText()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (m *MaskInverseText) UnmarshalText(text []byte) error {
+	return (*MaskInverse)(m).UnmarshalText(text)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m MaskInverseText) MarshalJSON() ([]byte, error) {
+	return textToJSON(m.MarshalText())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MaskInverseText) UnmarshalJSON(data []byte) error {
+	return (*MaskInverse)(m).UnmarshalJSON(data)
+}
+
+// MaskObject is a Mask which is marshaled to JSON as a nested object like {"a":{"b":{}},"c":{}}.
+type MaskObject Mask
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m MaskObject) MarshalJSON() ([]byte, error) {
+	return objectToJSON(filterToJSONObject(Mask(m)))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MaskObject) UnmarshalJSON(data []byte) error {
+	return (*Mask)(m).UnmarshalJSON(data)
+}
+
+// MaskInverseObject is a MaskInverse which is marshaled to JSON as a nested object like {"a":{"b":{}},"c":{}}.
+type MaskInverseObject MaskInverse
+
+// MarshalJSON implements the json.Marshaler interface.
+func (m MaskInverseObject) MarshalJSON() ([]byte, error) {
+	return objectToJSON(filterToJSONObject(MaskInverse(m)))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (m *MaskInverseObject) UnmarshalJSON(data []byte) error {
+	return (*MaskInverse)(m).UnmarshalJSON(data)
+}
+
+// MaskFlag returns a flag.Value for the given Mask, so it can be used with flag.Var. See MaskText for the format.
+func MaskFlag(m *Mask) flag.Value {
+	return &textFlag{(*MaskText)(m)}
+}
+
+// MaskInverseFlag returns a flag.Value for the given MaskInverse, so it can be used with flag.Var.
+// See MaskInverseText for the format.
+func MaskInverseFlag(m *MaskInverse) flag.Value {
+	return &textFlag{(*MaskInverseText)(m)}
+}
+
+// textFlag implements flag.Value on top of the encoding.TextMarshaler and encoding.TextUnmarshaler interfaces.
+type textFlag struct {
+	value interface {
+		encoding.TextMarshaler
+		encoding.TextUnmarshaler
+	}
+}
+
+func (f *textFlag) String() string {
+	if f == nil || f.value == nil {
+		return ""
+	}
+	text, err := f.value.MarshalText()
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+func (f *textFlag) Set(s string) error {
+	return f.value.UnmarshalText([]byte(s))
+}
+
+// filterFromText creates a new FieldFilterContainer from either comma separated paths or the ParseFieldFilter format.
+func filterFromText(text string, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	if indexUnquoted(text, '{') >= 0 {
+		return ParseFieldFilter(text, func(s string) string { return s }, filter)
+	}
+	if strings.TrimSpace(text) == "" {
+		return filter(), nil
+	}
+	var paths []string
+	for {
+		end := indexUnquoted(text, ',')
+		if end < 0 {
+			paths = append(paths, strings.TrimSpace(text))
+			break
+		}
+		paths = append(paths, strings.TrimSpace(text[:end]))
+		text = text[end+1:]
+	}
+	return FieldFilterFromPaths(paths, func(s string) string { return s }, filter)
+}
+
+// indexUnquoted returns the index of the first given character in s which is not enclosed in backticks or -1.
+func indexUnquoted(s string, c byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '`':
+			quoted = !quoted
+		case c:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// textToJSON returns the given text representation of a mask as a JSON string.
+func textToJSON(text []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// objectToJSON returns the JSON encoding of the given nested map representation of a mask.
+func objectToJSON(object map[string]interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(object)
+}
+
+// filterToJSONObject returns the nested map representation of the given filter.
+func filterToJSONObject(filter FieldFilterContainer) (map[string]interface{}, error) {
+	fieldNames, err := filterFieldNames(filter)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(fieldNames))
+	for _, fieldName := range fieldNames {
+		sub, _ := filter.Get(fieldName)
+		if result[fieldName], err = filterToJSONObject(sub); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// filterFromJSON creates a new FieldFilterContainer from either a JSON string or a JSON object.
+// Nil is returned for a JSON null.
+func filterFromJSON(data []byte, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil, nil
+	case bytes.HasPrefix(data, []byte(`"`)):
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return filterFromText(text, filter)
+	default:
+		return filterFromJSONObject(data, filter)
+	}
+}
+
+// filterFromJSONObject creates a new FieldFilterContainer from a nested JSON object. Leaves are either empty objects or
+// nulls.
+func filterFromJSONObject(data []byte, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.Wrap(err, "mask must be a JSON string or object")
+	}
+	container := filter()
+	for fieldName, value := range fields {
+		sub := filter()
+		if !bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			var err error
+			if sub, err = filterFromJSONObject(value, filter); err != nil {
+				return nil, err
+			}
+		}
+		container.Set(fieldName, sub)
+	}
+	return container, nil
+}
@@ -0,0 +1,144 @@
+package fieldmask_utils_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+)
+
+func TestMaskText_MarshalText(t *testing.T) {
+	testCases := []struct {
+		mask         fieldmask_utils.Mask
+		expectedText string
+	}{
+		{fieldmask_utils.MaskFromString("d,a{c,b}"), "a.b,a.c,d"},
+		{fieldmask_utils.Mask{"meta": fieldmask_utils.Mask{"a,b": fieldmask_utils.Mask{}}}, "meta.`a,b`"},
+		{fieldmask_utils.Mask{}, ""},
+	}
+	for _, testCase := range testCases {
+		text, err := fieldmask_utils.MaskText(testCase.mask).MarshalText()
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expectedText, string(text))
+
+		var mask fieldmask_utils.Mask
+		require.NoError(t, mask.UnmarshalText(text))
+		assert.Equal(t, testCase.mask, mask)
+	}
+}
+
+func TestMask_UnmarshalText(t *testing.T) {
+	testCases := []struct {
+		text         string
+		expectedMask fieldmask_utils.Mask
+	}{
+		{"a.b, a.c ,d", fieldmask_utils.MaskFromString("a{b,c},d")},
+		{"a{b,c},d", fieldmask_utils.MaskFromString("a{b,c},d")},
+		{"meta.`{x}`", fieldmask_utils.Mask{"meta": fieldmask_utils.Mask{"{x}": fieldmask_utils.Mask{}}}},
+		{" ", fieldmask_utils.Mask{}},
+	}
+	for _, testCase := range testCases {
+		var mask fieldmask_utils.Mask
+		require.NoError(t, mask.UnmarshalText([]byte(testCase.text)), testCase.text)
+		assert.Equal(t, testCase.expectedMask, mask, testCase.text)
+	}
+
+	for _, text := range []string{"a,,b", "a.", "a{b", "a,"} {
+		var mask fieldmask_utils.Mask
+		assert.Error(t, mask.UnmarshalText([]byte(text)), text)
+	}
+}
+
+func TestMask_JSON(t *testing.T) {
+	type config struct {
+		Mask        fieldmask_utils.Mask              `json:"mask"`
+		MaskText    fieldmask_utils.MaskText          `json:"mask_text"`
+		MaskObject  fieldmask_utils.MaskObject        `json:"mask_object"`
+		MaskInverse fieldmask_utils.MaskInverse       `json:"mask_inverse"`
+		InverseText fieldmask_utils.MaskInverseText   `json:"inverse_text"`
+		Inverse     fieldmask_utils.MaskInverseObject `json:"inverse"`
+	}
+	c := config{
+		Mask:        fieldmask_utils.MaskFromString("a{b,c},d"),
+		MaskText:    fieldmask_utils.MaskText(fieldmask_utils.MaskFromString("a{b,c},d")),
+		MaskObject:  fieldmask_utils.MaskObject(fieldmask_utils.MaskFromString("a{b,c},d")),
+		MaskInverse: fieldmask_utils.MaskInverseFromString("e{f}"),
+		InverseText: fieldmask_utils.MaskInverseText(fieldmask_utils.MaskInverseFromString("e{f}")),
+		Inverse:     fieldmask_utils.MaskInverseObject(fieldmask_utils.MaskInverseFromString("e{f}")),
+	}
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"mask": {"a": {"b": {}, "c": {}}, "d": {}},
+		"mask_text": "a.b,a.c,d",
+		"mask_object": {"a": {"b": {}, "c": {}}, "d": {}},
+		"mask_inverse": {"e": {"f": {}}},
+		"inverse_text": "e.f",
+		"inverse": {"e": {"f": {}}}
+	}`, string(data))
+
+	var decoded config
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, c, decoded)
+}
+
+func TestMask_JSONDefaultEncoding(t *testing.T) {
+	// The masks are encoded as the plain maps they are.
+	mask := fieldmask_utils.Mask{"a": fieldmask_utils.Mask{"b": nil}, "c": fieldmask_utils.Mask{}}
+	data, err := json.Marshal(mask)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": {"b": null}, "c": {}}`, string(data))
+	data, err = json.Marshal(fieldmask_utils.MaskInverse(mask))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a": {"b": null}, "c": {}}`, string(data))
+}
+
+func TestMask_UnmarshalJSON(t *testing.T) {
+	testCases := []struct {
+		data         string
+		expectedMask string
+	}{
+		{`"a.b,c"`, "a{b},c"},
+		{`{"a": {"b": null}, "c": {}}`, "a{b},c"},
+		{`""`, ""},
+		{`{}`, ""},
+	}
+	for _, testCase := range testCases {
+		var mask fieldmask_utils.Mask
+		require.NoError(t, json.Unmarshal([]byte(testCase.data), &mask), testCase.data)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expectedMask), mask, testCase.data)
+
+		var maskInverse fieldmask_utils.MaskInverse
+		require.NoError(t, json.Unmarshal([]byte(testCase.data), &maskInverse), testCase.data)
+		assert.Equal(t, fieldmask_utils.MaskInverseFromString(testCase.expectedMask), maskInverse, testCase.data)
+	}
+
+	mask := fieldmask_utils.MaskFromString("a")
+	require.NoError(t, json.Unmarshal([]byte("null"), &mask))
+	assert.Equal(t, fieldmask_utils.MaskFromString("a"), mask)
+
+	for _, data := range []string{`1`, `{"a": true}`, `"a..b"`, `["a"]`} {
+		assert.Error(t, json.Unmarshal([]byte(data), &mask), data)
+	}
+}
+
+func TestMaskFlag(t *testing.T) {
+	var mask fieldmask_utils.Mask
+	var maskInverse fieldmask_utils.MaskInverse
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(fieldmask_utils.MaskFlag(&mask), "mask", "field mask")
+	flags.Var(fieldmask_utils.MaskInverseFlag(&maskInverse), "exclude", "fields to exclude")
+
+	require.NoError(t, flags.Parse([]string{"-mask", "a.b,c", "-exclude", "d{e}"}))
+	assert.Equal(t, fieldmask_utils.MaskFromString("a{b},c"), mask)
+	assert.Equal(t, fieldmask_utils.MaskInverseFromString("d{e}"), maskInverse)
+	assert.Equal(t, "a.b,c", flags.Lookup("mask").Value.String())
+
+	assert.Error(t, flags.Parse([]string{"-mask", "a{"}))
+}
@@ -2,9 +2,9 @@ package fieldmask_utils
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
-	"github.com/pkg/errors"
 	"google.golang.org/genproto/protobuf/field_mask"
 )
 
@@ -43,6 +43,10 @@ func (m Mask) Set(fieldName string, filter FieldFilterContainer) {
 // Compile time interface check.
 var _ FieldFilter = Mask{}
 
+// Wildcard is a field name that matches all the fields (or map entries, or list items) at its level, e.g.
+// "ImagesBySize{*{OriginalUrl}}". An entry for a particular field name takes precedence over the Wildcard.
+const Wildcard = "*"
+
 // Filter returns true for those fieldNames that exist in the underlying map.
 // Field names that start with "XXX_" are ignored as unexported.
 func (m Mask) Filter(fieldName string) (FieldFilter, bool) {
@@ -52,6 +56,9 @@ func (m Mask) Filter(fieldName string) (FieldFilter, bool) {
 	}
 	subFilter, ok := m[fieldName]
 	if !ok {
+		if wildcard, hasWildcard := m[Wildcard]; hasWildcard && !strings.HasPrefix(fieldName, "XXX_") {
+			return wildcard, true
+		}
 		subFilter = Mask{}
 	}
 	return subFilter, ok
@@ -62,15 +69,24 @@ func (m Mask) IsEmpty() bool {
 	return len(m) == 0
 }
 
+// mapToString returns the canonical string representation of the given filter: field names are sorted and escaped
+// with quoteFilterName, so the result can be parsed back by ParseFieldFilter.
 func mapToString(m map[string]FieldFilterContainer) string {
 	if len(m) == 0 {
 		return ""
 	}
-	var result []string
-	for fieldName, maskNode := range m {
-		r := fieldName
+	fieldNames := make([]string, 0, len(m))
+	for fieldName := range m {
+		fieldNames = append(fieldNames, fieldName)
+	}
+	sort.Strings(fieldNames)
+	result := make([]string, 0, len(m))
+	for _, fieldName := range fieldNames {
+		r := quoteFilterName(fieldName)
 		var sub string
-		if stringer, ok := maskNode.(fmt.Stringer); ok {
+		if maskNode := m[fieldName]; maskNode == nil {
+			sub = ""
+		} else if stringer, ok := maskNode.(fmt.Stringer); ok {
 			sub = stringer.String()
 		} else {
 			sub = fmt.Sprint(maskNode)
@@ -83,10 +99,58 @@ func mapToString(m map[string]FieldFilterContainer) string {
 	return strings.Join(result, ",")
 }
 
+// String returns the canonical string representation of the mask like "a,b{c,d{e}}" with the field names sorted.
+// The result can be parsed back with ParseMask.
 func (m Mask) String() string {
 	return mapToString(m)
 }
 
+// Paths returns the sorted field paths of the leaves of the mask, e.g. []string{"a.b", "a.c", "d"} for "a{b,c},d".
+// Every segment except the Wildcard is passed through the naming function (unless it is nil) and enclosed in backticks
+// if it is not a valid field name (e.g. a map key with dots or spaces), so the paths can be parsed by MaskFromPaths.
+// An error is returned if the mask has a nested FieldFilterContainer which is not based on a map, as its field names
+// can not be listed.
+func (m Mask) Paths(naming func(string) string) ([]string, error) {
+	var paths []string
+	if err := appendPaths(&paths, m, "", naming); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ToProtoFieldMask returns a FieldMask with the paths of the mask. See Paths for details.
+func (m Mask) ToProtoFieldMask(naming func(string) string) (*field_mask.FieldMask, error) {
+	paths, err := m.Paths(naming)
+	if err != nil {
+		return nil, err
+	}
+	return &field_mask.FieldMask{Paths: paths}, nil
+}
+
+// appendPaths appends the paths of the leaves of the given filter prefixed with the given path.
+func appendPaths(paths *[]string, filter FieldFilterContainer, prefix string, naming func(string) string) error {
+	fieldNames, err := filterFieldNames(filter)
+	if err != nil {
+		return err
+	}
+	for _, fieldName := range fieldNames {
+		segment := fieldName
+		if naming != nil && fieldName != Wildcard {
+			segment = naming(segment)
+		}
+		path := joinPath(prefix, formatPathSegment(segment))
+		if sub, _ := filter.Get(fieldName); !isEmptyFilter(sub) {
+			if err := appendPaths(paths, sub, path, naming); err != nil {
+				return err
+			}
+		} else {
+			*paths = append(*paths, path)
+		}
+	}
+	return nil
+}
+
 // MaskInverse is an inversed version of a Mask (will copy all the fields except those mentioned in the mask).
 type MaskInverse map[string]FieldFilterContainer
 
@@ -105,6 +169,9 @@ func (m MaskInverse) Set(fieldName string, filter FieldFilterContainer) {
 // Field names that start with "XXX_" are ignored as unexported.
 func (m MaskInverse) Filter(fieldName string) (FieldFilter, bool) {
 	subFilter, ok := m[fieldName]
+	if !ok {
+		subFilter, ok = m[Wildcard]
+	}
 	if !ok {
 		return MaskInverse{}, !strings.HasPrefix(fieldName, "XXX_")
 	}
@@ -119,25 +186,47 @@ func (m MaskInverse) IsEmpty() bool {
 	return len(m) == 0
 }
 
+// String returns the canonical string representation of the mask. See Mask.String for details.
 func (m MaskInverse) String() string {
 	return mapToString(m)
 }
 
 // MaskFromProtoFieldMask creates a Mask from the given FieldMask.
+// The field names are resolved with the naming function only, so a oneof member is addressed directly as long as the
+// naming function returns the name of its Go field (e.g. "male_name" -> "MaleName"). Use MaskFromProtoFieldMaskFor to
+// resolve the field names (including the oneof members) with the descriptor of a message instead.
 func MaskFromProtoFieldMask(fm *field_mask.FieldMask, naming func(string) string) (Mask, error) {
 	return MaskFromPaths(fm.GetPaths(), naming)
 }
 
+// MaskFromProtoFieldMaskWithOptions is the same as MaskFromProtoFieldMask with the size of the result limited by the
+// given options. See FieldFilterFromPathsWithOptions for details.
+func MaskFromProtoFieldMaskWithOptions(fm *field_mask.FieldMask, naming func(string) string, opts ...ParseOption) (Mask, error) {
+	return MaskFromPathsWithOptions(fm.GetPaths(), naming, opts...)
+}
+
 // MaskInverseFromProtoFieldMask creates a MaskInverse from the given FieldMask.
 func MaskInverseFromProtoFieldMask(fm *field_mask.FieldMask, naming func(string) string) (MaskInverse, error) {
 	return MaskInverseFromPaths(fm.GetPaths(), naming)
 }
 
+// MaskInverseFromProtoFieldMaskWithOptions is the same as MaskInverseFromProtoFieldMask with the size of the result
+// limited by the given options. See FieldFilterFromPathsWithOptions for details.
+func MaskInverseFromProtoFieldMaskWithOptions(fm *field_mask.FieldMask, naming func(string) string, opts ...ParseOption) (MaskInverse, error) {
+	return MaskInverseFromPathsWithOptions(fm.GetPaths(), naming, opts...)
+}
+
 // MaskFromPaths creates a new Mask from the given paths.
 func MaskFromPaths(paths []string, naming func(string) string) (Mask, error) {
-	mask, err := FieldFilterFromPaths(paths, naming, func() FieldFilterContainer {
+	return MaskFromPathsWithOptions(paths, naming)
+}
+
+// MaskFromPathsWithOptions is the same as MaskFromPaths with the size of the result limited by the given options.
+// See FieldFilterFromPathsWithOptions for details.
+func MaskFromPathsWithOptions(paths []string, naming func(string) string, opts ...ParseOption) (Mask, error) {
+	mask, err := FieldFilterFromPathsWithOptions(paths, naming, func() FieldFilterContainer {
 		return make(Mask)
-	})
+	}, opts...)
 	if mask != nil {
 		return mask.(Mask), err
 	}
@@ -146,9 +235,15 @@ func MaskFromPaths(paths []string, naming func(string) string) (Mask, error) {
 
 // MaskInverseFromPaths creates a new MaskInverse from the given paths.
 func MaskInverseFromPaths(paths []string, naming func(string) string) (MaskInverse, error) {
-	mask, err := FieldFilterFromPaths(paths, naming, func() FieldFilterContainer {
+	return MaskInverseFromPathsWithOptions(paths, naming)
+}
+
+// MaskInverseFromPathsWithOptions is the same as MaskInverseFromPaths with the size of the result limited by the given
+// options. See FieldFilterFromPathsWithOptions for details.
+func MaskInverseFromPathsWithOptions(paths []string, naming func(string) string, opts ...ParseOption) (MaskInverse, error) {
+	mask, err := FieldFilterFromPathsWithOptions(paths, naming, func() FieldFilterContainer {
 		return make(MaskInverse)
-	})
+	}, opts...)
 	if mask != nil {
 		return mask.(MaskInverse), err
 	}
@@ -156,21 +251,35 @@ func MaskInverseFromPaths(paths []string, naming func(string) string) (MaskInver
 }
 
 // FieldFilterFromPaths creates a new FieldFilter from the given paths.
+// Paths follow the AIP-161 syntax: segments enclosed in backticks (e.g. "meta.`key.with.dots`") are used as is,
+// "*" is the Wildcard, all the other segments are passed through the naming function.
 func FieldFilterFromPaths(paths []string, naming func(string) string, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	return FieldFilterFromPathsWithOptions(paths, naming, filter)
+}
+
+// FieldFilterFromPathsWithOptions is the same as FieldFilterFromPaths with the size of the result limited by the
+// ParseOption functions like WithMaxPaths. The limits are checked while the paths are scanned and an error matching
+// ErrMaskTooLarge is returned as soon as any of them is exceeded.
+func FieldFilterFromPathsWithOptions(paths []string, naming func(string) string, filter func() FieldFilterContainer, opts ...ParseOption) (FieldFilterContainer, error) {
+	limiter := newPathsLimiter(opts)
+	if err := limiter.checkPaths(paths); err != nil {
+		return nil, err
+	}
 	root := filter()
 	for _, path := range paths {
+		segments, err := splitPath(path, limiter)
+		if err != nil {
+			return nil, pathError(path, err)
+		}
 		mask := root
-		for _, fieldName := range strings.Split(path, ".") {
-			if fieldName == "" {
-				return nil, errors.Errorf("invalid fieldName FieldFilter format: \"%s\"", path)
+		for _, segment := range segments {
+			fieldName := segment.name
+			if !segment.quoted && !segment.isWildcard() {
+				fieldName = naming(fieldName)
 			}
-			newFieldName := naming(fieldName)
-			subNode, ok := mask.Get(newFieldName)
-			if !ok {
-				mask.Set(newFieldName, filter())
-				subNode, _ = mask.Get(newFieldName)
+			if mask, err = limiter.subContainer(mask, fieldName, filter); err != nil {
+				return nil, err
 			}
-			mask = subNode
 		}
 	}
 	return root, nil
@@ -195,7 +304,7 @@ func MaskInverseFromString(s string) MaskInverse {
 // FieldFilterFromString creates a new FieldFilterContainer from string.
 // Input string is supposed to be a valid string representation of a FieldFilter like "a,b,c{d,e{f,g}},d".
 // Use it in tests only as the input string is not validated and the underlying function panics in case of a
-// parse error.
+// parse error. Use ParseFieldFilter to parse untrusted input.
 func FieldFilterFromString(input string, filter func() FieldFilterContainer) FieldFilterContainer {
 	var fieldName []string
 	mask := filter()
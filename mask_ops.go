@@ -0,0 +1,463 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Union, Intersect and Subtract treat their arguments as trees of field paths: every entry of a container is a path
+// segment and an empty (or nil) sub-filter selects the whole subtree of its field, e.g. "a{b}" selects "a.b" and
+// everything below it. An empty container at the root selects no paths at all. An entry for the Wildcard selects
+// all the fields (map entries) which do not have their own entry.
+// The result is always a new tree of the same type as the first argument (the second one if the first one is nil): the
+// arguments are not modified and the result does not share any nodes with them. Only map based containers like Mask
+// and MaskInverse are supported, an error is returned for the other ones.
+
+// Union returns a new FieldFilterContainer with the paths selected by either a or b. Nil is returned if both of them
+// are nil.
+func Union(a, b FieldFilterContainer) (FieldFilterContainer, error) {
+	like := filterLike(a, b)
+	if isEmptyFilter(a) {
+		return cloneFilter(b, like)
+	}
+	if isEmptyFilter(b) {
+		return cloneFilter(a, like)
+	}
+	return union(a, b, like)
+}
+
+// Intersect returns a new FieldFilterContainer with the paths selected by both a and b.
+// Note that the result is empty if a and b do not have any paths in common, which copies all the fields when used with
+// StructToStruct or StructToMap: check it with IsEmpty first.
+func Intersect(a, b FieldFilterContainer) (FieldFilterContainer, error) {
+	like := filterLike(a, b)
+	if isEmptyFilter(a) || isEmptyFilter(b) {
+		return newFilterLike(like), nil
+	}
+	result, err := intersect(a, b, like)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return newFilterLike(like), nil
+	}
+	return result, nil
+}
+
+// Subtract returns a new FieldFilterContainer with the paths selected by a but not by b.
+// The result is empty if no paths are left, see Intersect.
+// Removing a part of a subtree which is selected by a as a whole requires the list of fields of that subtree, so the
+// fields are looked up in the given type (a struct or a pointer to a struct the filters are applied to).
+// An error is returned if the type is nil or does not have such a subtree, or if the subtree is a map: map keys can not
+// be enumerated.
+func Subtract(a, b FieldFilterContainer, typ reflect.Type) (FieldFilterContainer, error) {
+	like := filterLike(a, b)
+	if isEmptyFilter(a) || isEmptyFilter(b) {
+		return cloneFilter(a, like)
+	}
+	result, err := subtract(a, b, typ, "", like)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return newFilterLike(like), nil
+	}
+	return result, nil
+}
+
+// Complement turns the given Mask into the equivalent MaskInverse (and vice versa) for the given type (a struct or
+// a pointer to a struct): both of them copy the same fields of that type when used with StructToStruct or StructToMap.
+// Members of a protobuf oneof are addressed by their own names in the result.
+// An error is returned if the mask can not be expressed the other way, e.g. a MaskInverse which excludes all the fields
+// or a mask with individual map keys (only the Wildcard is supported for maps).
+func Complement(mask FieldFilterContainer, typ reflect.Type) (FieldFilterContainer, error) {
+	var filter func() FieldFilterContainer
+	switch mask.(type) {
+	case Mask:
+		filter = func() FieldFilterContainer { return make(MaskInverse) }
+	case MaskInverse:
+		filter = func() FieldFilterContainer { return make(Mask) }
+	default:
+		return nil, errors.Errorf("unsupported FieldFilterContainer %T", mask)
+	}
+	if mask.IsEmpty() {
+		return filter(), nil
+	}
+	result, err := complement(mask, typ, "", filter)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := mask.(MaskInverse); ok && result.IsEmpty() {
+		return nil, errors.Errorf("%T selects no fields of %s and has no complement", mask, typ)
+	}
+	return result, nil
+}
+
+func union(a, b, like FieldFilterContainer) (FieldFilterContainer, error) {
+	if isEmptyFilter(a) || isEmptyFilter(b) {
+		return newFilterLike(like), nil
+	}
+	fieldNames, err := filterFieldNames(a, b)
+	if err != nil {
+		return nil, err
+	}
+	result := newFilterLike(like)
+	for _, fieldName := range fieldNames {
+		subA, okA := lookupFilter(a, fieldName)
+		subB, okB := lookupFilter(b, fieldName)
+		var sub FieldFilterContainer
+		switch {
+		case okA && okB:
+			sub, err = union(subA, subB, like)
+		case okA:
+			sub, err = cloneFilter(subA, like)
+		case okB:
+			sub, err = cloneFilter(subB, like)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Set(fieldName, sub)
+	}
+	return result, nil
+}
+
+// intersect returns the intersection of the given subtrees or nil if they do not have any paths in common.
+func intersect(a, b, like FieldFilterContainer) (FieldFilterContainer, error) {
+	if isEmptyFilter(a) {
+		return cloneFilter(b, like)
+	}
+	if isEmptyFilter(b) {
+		return cloneFilter(a, like)
+	}
+	fieldNames, err := filterFieldNames(a, b)
+	if err != nil {
+		return nil, err
+	}
+	result := newFilterLike(like)
+	for _, fieldName := range fieldNames {
+		subA, okA := lookupFilter(a, fieldName)
+		subB, okB := lookupFilter(b, fieldName)
+		if !okA || !okB {
+			continue
+		}
+		sub, err := intersect(subA, subB, like)
+		if err != nil {
+			return nil, err
+		}
+		if sub != nil {
+			result.Set(fieldName, sub)
+		}
+	}
+	if result.IsEmpty() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// subtract returns the paths of the subtree a which are not in the subtree b or nil if there are no such paths.
+// typ is the type of the field the subtrees belong to, path is used in errors only.
+func subtract(a, b FieldFilterContainer, typ reflect.Type, path string, like FieldFilterContainer) (FieldFilterContainer, error) {
+	if isEmptyFilter(b) {
+		return nil, nil
+	}
+	expand := isEmptyFilter(a)
+	if !expand && mentionsField(a, Wildcard) {
+		var err error
+		if expand, err = hasOwnFields(b, a); err != nil {
+			return nil, err
+		}
+	}
+	if expand {
+		// The fields of the subtree need to be listed explicitly to remove some of them.
+		expanded, err := expandFilter(a, typ, path, like)
+		if err != nil {
+			return nil, err
+		}
+		a = expanded
+	}
+	fieldNames, err := filterFieldNames(a)
+	if err != nil {
+		return nil, err
+	}
+	result := newFilterLike(like)
+	for _, fieldName := range fieldNames {
+		subA, _ := a.Get(fieldName)
+		subB, ok := lookupFilter(b, fieldName)
+		if !ok {
+			sub, err := cloneFilter(subA, like)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(fieldName, sub)
+			continue
+		}
+		sub, err := subtract(subA, subB, childType(typ, fieldName), joinPath(path, fieldName), like)
+		if err != nil {
+			return nil, err
+		}
+		if sub != nil {
+			result.Set(fieldName, sub)
+		}
+	}
+	if result.IsEmpty() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// expandFilter returns a copy of the given subtree with all the fields of the given type listed explicitly in place of
+// the whole subtree or in place of the Wildcard.
+func expandFilter(filter FieldFilterContainer, typ reflect.Type, path string, like FieldFilterContainer) (FieldFilterContainer, error) {
+	fields, ok := listFields(typ)
+	if !ok {
+		return nil, errors.Errorf("can not list the fields of %q: %v is not a struct", path, typ)
+	}
+	result := newFilterLike(like)
+	for _, field := range fields {
+		if sub, ok := lookupFilter(filter, field.name); ok || isEmptyFilter(filter) {
+			clone, err := cloneFilter(sub, like)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(field.name, clone)
+		}
+	}
+	return result, nil
+}
+
+// complement returns the complement of the given non-empty subtree for the given type.
+func complement(mask FieldFilterContainer, typ reflect.Type, path string, filter func() FieldFilterContainer) (FieldFilterContainer, error) {
+	if isListType(typ) && mentionsField(mask, Wildcard) {
+		// The Wildcard of a list selects its items.
+		items, _ := mask.Get(Wildcard)
+		if isEmptyFilter(items) {
+			return filter(), nil
+		}
+		mask = items
+	}
+	elemType := indirectType(typ)
+	result := filter()
+	if elemType != nil && elemType.Kind() == reflect.Map {
+		fieldNames, err := filterFieldNames(mask)
+		if err != nil {
+			return nil, err
+		}
+		items, ok := mask.Get(Wildcard)
+		if !ok || len(fieldNames) != 1 {
+			return nil, errors.Errorf("can not complement the map %q: only the Wildcard is supported for map keys", path)
+		}
+		if !isEmptyFilter(items) {
+			sub, err := complement(items, elemType.Elem(), joinPath(path, Wildcard), filter)
+			if err != nil {
+				return nil, err
+			}
+			if !sub.IsEmpty() {
+				result.Set(Wildcard, sub)
+			}
+		}
+		return result, nil
+	}
+	fields, ok := listFields(typ)
+	if !ok {
+		return nil, errors.Errorf("can not list the fields of %q: %v is not a struct", path, typ)
+	}
+	for _, field := range fields {
+		sub, ok := lookupFilter(mask, field.name)
+		if !ok && field.oneof != "" {
+			// The member may be addressed through the oneof field.
+			if sub, ok = lookupFilter(mask, field.oneof); ok && !isEmptyFilter(sub) {
+				sub, ok = lookupFilter(sub, field.name)
+			}
+		}
+		if !ok {
+			result.Set(field.name, filter())
+			continue
+		}
+		if isEmptyFilter(sub) {
+			continue
+		}
+		c, err := complement(sub, field.typ, joinPath(path, field.name), filter)
+		if err != nil {
+			return nil, err
+		}
+		if !c.IsEmpty() {
+			result.Set(field.name, c)
+		}
+	}
+	return result, nil
+}
+
+// listedField is a field of a struct as it is addressed in a FieldFilter.
+type listedField struct {
+	name string
+	typ  reflect.Type
+	// oneof is the name of the oneof field for protobuf oneof members.
+	oneof string
+}
+
+// listFields returns the fields of the struct held by the given type (possibly through pointers, slices or arrays).
+// Unexported and "XXX_" fields are skipped, protobuf oneof fields are replaced with their members.
+// Result is false if the type does not hold a struct.
+func listFields(typ reflect.Type) ([]listedField, bool) {
+	structType := indirectType(typ)
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, false
+	}
+	var fields []listedField
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+		if isOneof(f) {
+			if members := oneofMemberFields(structType, f); members != nil {
+				for _, member := range members {
+					fields = append(fields, listedField{name: member.Name, typ: member.Type, oneof: f.Name})
+				}
+				continue
+			}
+		}
+		fields = append(fields, listedField{name: f.Name, typ: f.Type})
+	}
+	return fields, true
+}
+
+// childType returns the type of the given field (or map value) of the given type. Nil is returned if it is unknown.
+func childType(typ reflect.Type, fieldName string) reflect.Type {
+	t := indirectType(typ)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+	fields, _ := listFields(t)
+	for _, field := range fields {
+		if field.name == fieldName {
+			return field.typ
+		}
+	}
+	return nil
+}
+
+// indirectType returns the type held by the given pointer, slice or array type.
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ != nil {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		default:
+			return typ
+		}
+	}
+	return nil
+}
+
+// isListType returns true if the given type is a slice or an array (or a pointer to one of them).
+func isListType(typ reflect.Type) bool {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array)
+}
+
+// lookupFilter returns the sub-filter for the given field name falling back to the Wildcard entry.
+func lookupFilter(filter FieldFilterContainer, fieldName string) (FieldFilterContainer, bool) {
+	sub, ok := filter.Get(fieldName)
+	if !ok && fieldName != Wildcard {
+		sub, ok = filter.Get(Wildcard)
+	}
+	return sub, ok
+}
+
+// hasOwnFields returns true if the given filter has entries other than the Wildcard which do not exist in the other
+// filter.
+func hasOwnFields(filter, other FieldFilterContainer) (bool, error) {
+	fieldNames, err := filterFieldNames(filter)
+	if err != nil {
+		return false, err
+	}
+	for _, fieldName := range fieldNames {
+		if _, ok := other.Get(fieldName); !ok && fieldName != Wildcard {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isEmptyFilter returns true if the given filter is nil or empty.
+func isEmptyFilter(filter FieldFilterContainer) bool {
+	return filter == nil || filter.IsEmpty()
+}
+
+// filterFieldNames returns the field names of all the given filters without duplicates.
+// The FieldFilterContainer interface can not list the field names, so an error is returned for the filters which are
+// not based on a map with string keys like Mask and MaskInverse.
+func filterFieldNames(filters ...FieldFilterContainer) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, filter := range filters {
+		if filter == nil {
+			continue
+		}
+		v := reflect.ValueOf(filter)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil, errors.Errorf("unsupported FieldFilterContainer %T: only map based containers are supported", filter)
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			name := iter.Key().String()
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// filterLike returns the first non-nil filter of the given ones. Nil is returned if all of them are nil.
+func filterLike(filters ...FieldFilterContainer) FieldFilterContainer {
+	for _, filter := range filters {
+		if filter != nil {
+			return filter
+		}
+	}
+	return nil
+}
+
+// newFilterLike returns a new empty container of the same type as the given one or nil if it is nil.
+func newFilterLike(like FieldFilterContainer) FieldFilterContainer {
+	if like == nil {
+		return nil
+	}
+	return reflect.MakeMap(reflect.TypeOf(like)).Interface().(FieldFilterContainer)
+}
+
+// cloneFilter returns a deep copy of the given filter made of containers of the same type as `like`.
+func cloneFilter(filter, like FieldFilterContainer) (FieldFilterContainer, error) {
+	fieldNames, err := filterFieldNames(filter)
+	if err != nil {
+		return nil, err
+	}
+	result := newFilterLike(like)
+	for _, fieldName := range fieldNames {
+		sub, _ := filter.Get(fieldName)
+		clone, err := cloneFilter(sub, like)
+		if err != nil {
+			return nil, err
+		}
+		result.Set(fieldName, clone)
+	}
+	return result, nil
+}
+
+func joinPath(path, fieldName string) string {
+	if path == "" {
+		return fieldName
+	}
+	return path + "." + fieldName
+}
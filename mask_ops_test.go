@@ -0,0 +1,299 @@
+package fieldmask_utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestUnion(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a,b", "b,c", "a,b,c"},
+		{"a{b}", "a{c}", "a{b,c}"},
+		{"a", "a{b}", "a"},
+		{"a{b{c}}", "a{b,d}", "a{b,d}"},
+		{"", "a{b}", "a{b}"},
+		{"a{b}", "", "a{b}"},
+		{"*{x}", "a{y}", "*{x},a{x,y}"},
+	}
+	for _, testCase := range testCases {
+		result, err := fieldmask_utils.Union(
+			fieldmask_utils.MaskFromString(testCase.a), fieldmask_utils.MaskFromString(testCase.b))
+		require.NoError(t, err, testCase)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expected), result, testCase)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"a,b", "b,c", "b"},
+		{"a{b,c}", "a{c,d}", "a{c}"},
+		{"a", "a{b{c}}", "a{b{c}}"},
+		{"a{b{c}}", "a", "a{b{c}}"},
+		{"a{b}", "a{c}", ""},
+		{"a,b", "c", ""},
+		{"", "a", ""},
+		{"*", "a{b},c", "a{b},c"},
+		{"*{x,y}", "a{y,z}", "a{y}"},
+	}
+	for _, testCase := range testCases {
+		result, err := fieldmask_utils.Intersect(
+			fieldmask_utils.MaskFromString(testCase.a), fieldmask_utils.MaskFromString(testCase.b))
+		require.NoError(t, err, testCase)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expected), result, testCase)
+	}
+}
+
+func TestIntersect_NoPathsInCommon(t *testing.T) {
+	// The empty result must not be used as a mask: it would copy all the fields.
+	result, err := fieldmask_utils.Intersect(
+		fieldmask_utils.MaskFromString("Role"), fieldmask_utils.MaskFromString("Username"))
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{}, result)
+}
+
+func TestSubtract(t *testing.T) {
+	type B struct {
+		X int
+		Y int
+	}
+	type A struct {
+		A                B
+		B                *B
+		C                []B
+		D                map[string]B
+		d                int
+		XXX_Unrecognized []byte
+	}
+	typ := reflect.TypeOf(&A{})
+	testCases := []struct {
+		a, b     string
+		expected string
+	}{
+		{"A,B", "B", "A"},
+		{"A{X,Y}", "A{Y}", "A{X}"},
+		{"A{X}", "A", ""},
+		{"A{X}", "B", "A{X}"},
+		{"A,B", "B{X}", "A,B{Y}"},
+		{"C", "C{Y}", "C{X}"},
+		{"*", "A,D", "B,C"},
+		{"*{X}", "A", "B{X},C{X},D{X}"},
+		{"A", "", "A"},
+		{"", "A", ""},
+	}
+	for _, testCase := range testCases {
+		result, err := fieldmask_utils.Subtract(
+			fieldmask_utils.MaskFromString(testCase.a), fieldmask_utils.MaskFromString(testCase.b), typ)
+		require.NoError(t, err, testCase)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expected), result, testCase)
+	}
+}
+
+func TestSubtract_Failure(t *testing.T) {
+	type A struct {
+		A int
+		B map[string]int
+	}
+	testCases := []struct {
+		a, b          string
+		typ           reflect.Type
+		expectedError string
+	}{
+		{"A", "A{X}", reflect.TypeOf(A{}), `can not list the fields of "A": int is not a struct`},
+		{"B", "B{key}", reflect.TypeOf(A{}), `can not list the fields of "B": map[string]int is not a struct`},
+		{"*", "A", nil, `can not list the fields of "": <nil> is not a struct`},
+		{"*", "*{X}", reflect.TypeOf(A{}), `can not list the fields of "*": <nil> is not a struct`},
+	}
+	for _, testCase := range testCases {
+		_, err := fieldmask_utils.Subtract(
+			fieldmask_utils.MaskFromString(testCase.a), fieldmask_utils.MaskFromString(testCase.b), testCase.typ)
+		assert.EqualError(t, err, testCase.expectedError)
+	}
+}
+
+func TestSetOperations_MaskInverse(t *testing.T) {
+	a := fieldmask_utils.MaskInverse{"a": nil, "b": fieldmask_utils.MaskInverse{"c": nil}}
+	b := fieldmask_utils.MaskInverseFromString("b{d},e")
+
+	result, err := fieldmask_utils.Union(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskInverseFromString("a,b{c,d},e"), result)
+	result, err = fieldmask_utils.Intersect(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskInverse{}, result)
+	result, err = fieldmask_utils.Subtract(a, b, nil)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskInverseFromString("a,b{c}"), result)
+	// The arguments are not modified.
+	assert.Equal(t, fieldmask_utils.MaskInverse{"a": nil, "b": fieldmask_utils.MaskInverse{"c": nil}}, a)
+}
+
+func TestUnion_DoesNotShareNodes(t *testing.T) {
+	a := fieldmask_utils.MaskFromString("a{b}")
+	result, err := fieldmask_utils.Union(a, fieldmask_utils.MaskFromString("c"))
+	require.NoError(t, err)
+	result.(fieldmask_utils.Mask)["a"].Set("x", fieldmask_utils.Mask{})
+	assert.Equal(t, fieldmask_utils.MaskFromString("a{b}"), a)
+}
+
+func TestSetOperations_Nil(t *testing.T) {
+	a := fieldmask_utils.MaskFromString("a{b}")
+
+	result, err := fieldmask_utils.Union(nil, a)
+	require.NoError(t, err)
+	assert.Equal(t, a, result)
+	result, err = fieldmask_utils.Union(a, nil)
+	require.NoError(t, err)
+	assert.Equal(t, a, result)
+	result, err = fieldmask_utils.Union(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	result, err = fieldmask_utils.Intersect(nil, a)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{}, result)
+
+	result, err = fieldmask_utils.Subtract(nil, a, nil)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{}, result)
+	result, err = fieldmask_utils.Subtract(a, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, a, result)
+}
+
+// structFilter is a FieldFilterContainer which is not based on a map.
+type structFilter struct {
+	fields map[string]fieldmask_utils.FieldFilterContainer
+}
+
+func (f *structFilter) Filter(fieldName string) (fieldmask_utils.FieldFilter, bool) {
+	return fieldmask_utils.Mask(f.fields).Filter(fieldName)
+}
+
+func (f *structFilter) IsEmpty() bool {
+	return len(f.fields) == 0
+}
+
+func (f *structFilter) Get(fieldName string) (fieldmask_utils.FieldFilterContainer, bool) {
+	sub, ok := f.fields[fieldName]
+	return sub, ok
+}
+
+func (f *structFilter) Set(fieldName string, filter fieldmask_utils.FieldFilterContainer) {
+	f.fields[fieldName] = filter
+}
+
+func TestSetOperations_UnsupportedContainer(t *testing.T) {
+	custom := &structFilter{fields: map[string]fieldmask_utils.FieldFilterContainer{"b": nil}}
+	mask := fieldmask_utils.Mask{"a": custom}
+
+	_, err := fieldmask_utils.Union(mask, fieldmask_utils.MaskFromString("c"))
+	assert.EqualError(t, err,
+		"unsupported FieldFilterContainer *fieldmask_utils_test.structFilter: only map based containers are supported")
+	_, err = fieldmask_utils.Intersect(mask, fieldmask_utils.MaskFromString("a{c}"))
+	assert.Error(t, err)
+	_, err = fieldmask_utils.Subtract(mask, fieldmask_utils.MaskFromString("c"), nil)
+	assert.Error(t, err)
+	_, err = mask.Paths(nil)
+	assert.Error(t, err)
+	_, err = fieldmask_utils.MaskText(mask).MarshalText()
+	assert.Error(t, err)
+	_, err = fieldmask_utils.MaskObject(mask).MarshalJSON()
+	assert.Error(t, err)
+}
+
+func TestComplement(t *testing.T) {
+	type B struct {
+		X int
+		Y int
+	}
+	type A struct {
+		A                B
+		B                *B
+		C                []B
+		D                map[string]B
+		d                int
+		XXX_Unrecognized []byte
+	}
+	typ := reflect.TypeOf(&A{})
+	testCases := []struct {
+		mask     string
+		expected string
+	}{
+		{"", ""},
+		{"A", "B,C,D"},
+		{"A{X},C", "A{Y},B,D"},
+		{"A{X,Y},B", "C,D"},
+		{"C{*{X}}", "A,B,C{Y},D"},
+		{"D{*{X}},B", "A,C,D{*{Y}}"},
+		{"*", ""},
+		{"A{*},B{X}", "B{Y},C,D"},
+		{"unknown", "A,B,C,D"},
+	}
+	for _, testCase := range testCases {
+		inverse, err := fieldmask_utils.Complement(fieldmask_utils.MaskFromString(testCase.mask), typ)
+		require.NoError(t, err, testCase.mask)
+		assert.Equal(t, fieldmask_utils.MaskInverseFromString(testCase.expected), inverse, testCase.mask)
+
+		mask, err := fieldmask_utils.Complement(inverse, typ)
+		if testCase.mask == "unknown" {
+			assert.Error(t, err)
+			continue
+		}
+		require.NoError(t, err, testCase.mask)
+		// Wildcards are replaced with the explicit fields in the round trip.
+		expected, err := fieldmask_utils.Complement(fieldmask_utils.MaskInverseFromString(testCase.expected), typ)
+		require.NoError(t, err)
+		assert.Equal(t, expected, mask, testCase.mask)
+	}
+}
+
+func TestComplement_CopiesTheSameFields(t *testing.T) {
+	src := &testproto.User{
+		Id:       1,
+		Username: "username",
+		Role:     testproto.Role_REGULAR,
+		Name:     &testproto.User_MaleName{MaleName: "male name"},
+		Avatar:   &testproto.Image{OriginalUrl: "original", ResizedUrl: "resized"},
+		Tags:     []string{"tag"},
+		Friends:  []*testproto.User{{Id: 2, Username: "friend"}},
+		Meta:     map[string]string{"color": "red"},
+	}
+	for _, paths := range []string{"Id,Avatar{ResizedUrl}", "MaleName,Friends{Username}", "FemaleName,Tags"} {
+		mask := fieldmask_utils.MaskFromString(paths)
+		inverse, err := fieldmask_utils.Complement(mask, reflect.TypeOf(src))
+		require.NoError(t, err)
+
+		dstMask := &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "female name"}}
+		require.NoError(t, fieldmask_utils.StructToStruct(mask, src, dstMask))
+		dstInverse := &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "female name"}}
+		require.NoError(t, fieldmask_utils.StructToStruct(inverse, src, dstInverse))
+		assert.Equal(t, dstMask, dstInverse, paths)
+	}
+}
+
+func TestComplement_Failure(t *testing.T) {
+	type A struct {
+		A int
+		B map[string]int
+	}
+	_, err := fieldmask_utils.Complement(fieldmask_utils.MaskFromString("B{key}"), reflect.TypeOf(A{}))
+	assert.EqualError(t, err, `can not complement the map "B": only the Wildcard is supported for map keys`)
+
+	_, err = fieldmask_utils.Complement(fieldmask_utils.MaskFromString("A{X}"), reflect.TypeOf(A{}))
+	assert.EqualError(t, err, `can not list the fields of "A": int is not a struct`)
+
+	_, err = fieldmask_utils.Complement(fieldmask_utils.MaskInverseFromString("A,B"), reflect.TypeOf(A{}))
+	assert.EqualError(t, err, "fieldmask_utils.MaskInverse selects no fields of fieldmask_utils_test.A and has no complement")
+}
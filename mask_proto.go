@@ -0,0 +1,347 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MaskFromProtoFieldMaskFor creates a Mask from the given FieldMask using the descriptor of the given message to
+// resolve the Go struct field names. See FieldFilterFromPathsFor for details.
+func MaskFromProtoFieldMaskFor(msg proto.Message, fm *field_mask.FieldMask, opts ...ParseOption) (Mask, error) {
+	return MaskFromPathsFor(msg, fm.GetPaths(), opts...)
+}
+
+// MaskInverseFromProtoFieldMaskFor creates a MaskInverse from the given FieldMask using the descriptor of the given
+// message to resolve the Go struct field names. See FieldFilterFromPathsFor for details.
+func MaskInverseFromProtoFieldMaskFor(msg proto.Message, fm *field_mask.FieldMask, opts ...ParseOption) (MaskInverse, error) {
+	return MaskInverseFromPathsFor(msg, fm.GetPaths(), opts...)
+}
+
+// MaskFromPathsFor creates a new Mask from the given paths using the descriptor of the given message.
+func MaskFromPathsFor(msg proto.Message, paths []string, opts ...ParseOption) (Mask, error) {
+	mask, err := FieldFilterFromPathsFor(msg, paths, func() FieldFilterContainer {
+		return make(Mask)
+	}, opts...)
+	if mask != nil {
+		return mask.(Mask), err
+	}
+	return nil, err
+}
+
+// MaskInverseFromPathsFor creates a new MaskInverse from the given paths using the descriptor of the given message.
+func MaskInverseFromPathsFor(msg proto.Message, paths []string, opts ...ParseOption) (MaskInverse, error) {
+	mask, err := FieldFilterFromPathsFor(msg, paths, func() FieldFilterContainer {
+		return make(MaskInverse)
+	}, opts...)
+	if mask != nil {
+		return mask.(MaskInverse), err
+	}
+	return nil, err
+}
+
+// FieldFilterFromPathsFor creates a new FieldFilter from the given protobuf field paths.
+// Unlike FieldFilterFromPaths it does not need a naming function: every path segment is resolved against the
+// descriptor of the given message (which may be a typed nil pointer) and mapped to the exact name of the Go struct
+// field generated for it. Members of a oneof are mapped to the field of their wrapper struct, e.g. "male_name" becomes
+// "MaleName" for the testproto.User message, which is understood by StructToStruct and StructToMap.
+// Paths follow the AIP-161 syntax: map keys may be enclosed in backticks and the Wildcard is allowed as the entire path,
+// in place of a map key or after a repeated field.
+// An error is returned for the first path segment that does not exist on the corresponding message.
+// The size of the result can be limited with the ParseOption functions the same way FieldFilterFromPathsWithOptions
+// does.
+func FieldFilterFromPathsFor(msg proto.Message, paths []string, filter func() FieldFilterContainer, opts ...ParseOption) (FieldFilterContainer, error) {
+	msgType := reflect.TypeOf(msg)
+	if !isGeneratedMessage(msgType) {
+		return nil, errors.Errorf("message type %s is not a generated Go struct", msgType)
+	}
+	limiter := newPathsLimiter(opts)
+	if err := limiter.checkPaths(paths); err != nil {
+		return nil, err
+	}
+	root := filter()
+	for _, path := range paths {
+		segments, err := splitPath(path, limiter)
+		if err != nil {
+			return nil, pathError(path, err)
+		}
+		mask := root
+		currentType := msgType
+		var currentMessage protoreflect.FullName
+		var parentName string
+		var mapKey protoreflect.FieldDescriptor
+		var list bool
+		for i, segment := range segments {
+			switch {
+			case mapKey != nil:
+				// The segment following a map field is a map key or a Wildcard.
+				if !segment.isWildcard() {
+					if err := validateMapKey(mapKey, segment.name); err != nil {
+						return nil, errors.Wrapf(err, "path %q", path)
+					}
+				}
+				if mask, err = limiter.subContainer(mask, segment.name, filter); err != nil {
+					return nil, err
+				}
+				mapKey = nil
+				parentName = segment.name
+				continue
+
+			case segment.isWildcard():
+				// A Wildcard selects all the items of a repeated field or all the fields if it is the entire path.
+				if !list && i > 0 {
+					return nil, errors.Errorf("path %q: wildcard is only allowed after a repeated or map field", path)
+				}
+				if mask, err = limiter.subContainer(mask, Wildcard, filter); err != nil {
+					return nil, err
+				}
+				list = false
+				continue
+
+			case segment.quoted:
+				return nil, errors.Errorf("path %q: quoted segment %q is only allowed as a map key", path, segment.name)
+
+			case currentType == nil && currentMessage == anyMessageName:
+				return nil, errors.Errorf("path %q: sub-paths of %s field %q are not supported", path, anyMessageName,
+					parentName)
+
+			case currentType == nil && currentMessage != "":
+				return nil, errors.Errorf("path %q: message %s of field %q is not a generated Go struct", path,
+					currentMessage, parentName)
+
+			case currentType == nil:
+				return nil, errors.Errorf("path %q: %q is not a message and has no fields", path, parentName)
+			}
+			field, err := protoGoFieldByName(currentType, segment.name)
+			if err != nil {
+				return nil, errors.Wrapf(err, "path %q", path)
+			}
+			if field == nil {
+				return nil, errors.Errorf("path %q: field %q does not exist in message %s", path, segment.name,
+					messageDescriptor(currentType).FullName())
+			}
+			if mask, err = limiter.subContainer(mask, field.field.Name, filter); err != nil {
+				return nil, err
+			}
+			currentType = field.message
+			currentMessage = field.messageName
+			mapKey = field.mapKey
+			list = field.list
+			parentName = segment.name
+		}
+	}
+	return root, nil
+}
+
+// subContainer returns the FieldFilterContainer for the given field name creating it if it does not exist yet.
+func subContainer(container FieldFilterContainer, fieldName string, filter func() FieldFilterContainer) FieldFilterContainer {
+	subNode, ok := container.Get(fieldName)
+	if !ok || subNode == nil {
+		subNode = filter()
+		container.Set(fieldName, subNode)
+	}
+	return subNode
+}
+
+// protoGoField describes how a protobuf field is laid out in a generated Go struct.
+type protoGoField struct {
+	// field is the Go struct field generated for the protobuf field. For oneof members it is the field of the oneof
+	// wrapper struct.
+	field reflect.StructField
+	// message is the Go type (a pointer to a generated struct) of the message held by the field (or by the items of a
+	// repeated field or by the values of a map). It is nil for scalar fields and google.protobuf.Any fields.
+	message reflect.Type
+	// messageName is the full name of that message even if its fields can not be traversed. It is empty for scalar
+	// fields.
+	messageName protoreflect.FullName
+	// mapKey is the descriptor of the map key. Only set for map fields.
+	mapKey protoreflect.FieldDescriptor
+	// list is true for repeated fields.
+	list bool
+}
+
+// protoGoFieldKey identifies the protoGoField resolved for a field name of a generated message type.
+type protoGoFieldKey struct {
+	msgType reflect.Type
+	name    string
+}
+
+// protoGoFieldCache holds the *protoGoField (nil if the message does not have the field) for every protoGoFieldKey
+// resolved without errors so far.
+var protoGoFieldCache sync.Map
+
+// protoGoFieldByName finds the Go struct field for the protobuf field with the given name in the given generated
+// message type. Nil is returned if the message does not have such a field.
+func protoGoFieldByName(msgType reflect.Type, name string) (*protoGoField, error) {
+	key := protoGoFieldKey{msgType: msgType, name: name}
+	if field, ok := protoGoFieldCache.Load(key); ok {
+		return field.(*protoGoField), nil
+	}
+	field, err := resolveProtoGoField(msgType, name)
+	if err != nil {
+		return nil, err
+	}
+	protoGoFieldCache.Store(key, field)
+	return field, nil
+}
+
+// resolveProtoGoField is the uncached version of protoGoFieldByName.
+func resolveProtoGoField(msgType reflect.Type, name string) (*protoGoField, error) {
+	msg := reflect.New(msgType.Elem()).Interface().(proto.Message)
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+	if fd == nil {
+		return nil, nil
+	}
+	structType := msgType.Elem()
+	if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		oneofField, ok := structFieldByTag(structType, "protobuf_oneof", string(od.Name()))
+		if !ok {
+			return nil, errors.Errorf("oneof %q is not found in %s", od.Name(), structType)
+		}
+		// Set the oneof case to find out the type of the wrapper struct generated for this member.
+		m.Set(fd, m.NewField(fd))
+		wrapperType := reflect.ValueOf(msg).Elem().FieldByIndex(oneofField.Index).Elem().Type()
+		memberField, ok := structFieldByProtoName(wrapperType.Elem(), name)
+		if !ok {
+			return nil, errors.Errorf("field %q is not found in %s", name, wrapperType.Elem())
+		}
+		return &protoGoField{
+			field:       memberField,
+			message:     protoMessageType(fd, memberField.Type),
+			messageName: protoMessageName(fd),
+		}, nil
+	}
+	goField, ok := structFieldByProtoName(structType, name)
+	if !ok {
+		return nil, errors.Errorf("field %q is not found in %s", name, structType)
+	}
+	return &protoGoField{
+		field:       goField,
+		message:     protoMessageType(fd, goField.Type),
+		messageName: protoMessageName(fd),
+		mapKey:      fd.MapKey(),
+		list:        fd.IsList(),
+	}, nil
+}
+
+// oneofMemberFields returns the fields of the wrapper structs generated for all the members of the given oneof field.
+// Nil is returned if the struct is not a generated protobuf message.
+func oneofMemberFields(structType reflect.Type, oneofField reflect.StructField) []reflect.StructField {
+	msgType := reflect.PtrTo(structType)
+	if !isGeneratedMessage(msgType) {
+		return nil
+	}
+	od := messageDescriptor(msgType).Oneofs().ByName(protoreflect.Name(oneofField.Tag.Get("protobuf_oneof")))
+	if od == nil {
+		return nil
+	}
+	var fields []reflect.StructField
+	for i := 0; i < od.Fields().Len(); i++ {
+		field, err := protoGoFieldByName(msgType, string(od.Fields().Get(i).Name()))
+		if err != nil || field == nil {
+			continue
+		}
+		fields = append(fields, field.field)
+	}
+	return fields
+}
+
+// protoMessageType returns the generated Go type of the message held by the given field or nil if the field is not a
+// message that can be traversed further.
+func protoMessageType(fd protoreflect.FieldDescriptor, goType reflect.Type) reflect.Type {
+	if fd.IsMap() {
+		fd = fd.MapValue()
+		goType = goType.Elem()
+	}
+	if fd.Message() == nil || fd.Message().FullName() == anyMessageName {
+		return nil
+	}
+	if goType.Kind() == reflect.Slice {
+		goType = goType.Elem()
+	}
+	if !isGeneratedMessage(goType) {
+		return nil
+	}
+	return goType
+}
+
+// protoMessageName returns the full name of the message held by the given field (or by its map values) or an empty
+// string if the field is not a message.
+func protoMessageName(fd protoreflect.FieldDescriptor) protoreflect.FullName {
+	if fd.IsMap() {
+		fd = fd.MapValue()
+	}
+	if fd.Message() == nil {
+		return ""
+	}
+	return fd.Message().FullName()
+}
+
+// validateMapKey returns an error if the given path segment is not a valid value of the given map key.
+func validateMapKey(mapKey protoreflect.FieldDescriptor, segment string) error {
+	var err error
+	switch mapKey.Kind() {
+	case protoreflect.BoolKind:
+		_, err = strconv.ParseBool(segment)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err = strconv.ParseInt(segment, 10, 32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		_, err = strconv.ParseInt(segment, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err = strconv.ParseUint(segment, 10, 32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		_, err = strconv.ParseUint(segment, 10, 64)
+	}
+	if err != nil {
+		return errors.Errorf("%q is not a valid %s map key", segment, mapKey.Kind())
+	}
+	return nil
+}
+
+// anyMessageName is the full name of the google.protobuf.Any message.
+const anyMessageName protoreflect.FullName = "google.protobuf.Any"
+
+// isGeneratedMessage returns true if the given type is a pointer to a generated protobuf struct.
+func isGeneratedMessage(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct &&
+		t.Implements(protoMessageInterface)
+}
+
+// protoMessageInterface is the type of the proto.Message interface.
+var protoMessageInterface = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// messageDescriptor returns the descriptor of the given generated message type.
+func messageDescriptor(msgType reflect.Type) protoreflect.MessageDescriptor {
+	return reflect.Zero(msgType).Interface().(proto.Message).ProtoReflect().Descriptor()
+}
+
+// structFieldByTag finds the struct field which has the given tag set to the given value.
+func structFieldByTag(structType reflect.Type, tag, value string) (reflect.StructField, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		if f.Tag.Get(tag) == value {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// structFieldByProtoName finds the struct field generated for the protobuf field with the given name.
+func structFieldByProtoName(structType reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		for _, part := range strings.Split(f.Tag.Get("protobuf"), ",") {
+			if part == "name="+name {
+				return f, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
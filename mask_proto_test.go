@@ -0,0 +1,96 @@
+package fieldmask_utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/protobuf/field_mask"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestMaskFromProtoFieldMaskFor_Success(t *testing.T) {
+	testCases := []struct {
+		paths        []string
+		expectedMask string
+	}{
+		{[]string{"id", "username"}, "Id,Username"},
+		{[]string{"avatar.original_url", "avatar.resized_url", "role"}, "Avatar{OriginalUrl,ResizedUrl},Role"},
+		{[]string{"extra_user", "friends.images.resized_url"}, "ExtraUser,Friends{Images{ResizedUrl}}"},
+		{[]string{"male_name"}, "MaleName"},
+		{[]string{"male_name", "female_name"}, "MaleName,FemaleName"},
+		{[]string{"friends.female_name", "meta"}, "Friends{FemaleName},Meta"},
+		{[]string{"meta.color", "meta.Size", "friends.meta.id"}, "Meta{color,Size},Friends{Meta{id}}"},
+		{[]string{"images_by_size.*.original_url", "images_by_size.small"}, "ImagesBySize{*{OriginalUrl},small}"},
+		{[]string{"meta.`color.dark`", "meta.`size`"}, "Meta{color.dark,size}"},
+		{[]string{"friends.*.username", "images.*"}, "Friends{*{Username}},Images{*}"},
+		{[]string{"*"}, "*"},
+		{[]string{}, ""},
+	}
+	for _, testCase := range testCases {
+		mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, &field_mask.FieldMask{Paths: testCase.paths})
+		require.NoError(t, err)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expectedMask), mask)
+
+		maskInverse, err := fieldmask_utils.MaskInverseFromProtoFieldMaskFor(
+			&testproto.User{}, &field_mask.FieldMask{Paths: testCase.paths})
+		require.NoError(t, err)
+		assert.Equal(t, fieldmask_utils.MaskInverseFromString(testCase.expectedMask), maskInverse)
+	}
+}
+
+func TestMaskFromProtoFieldMaskFor_NilMessage(t *testing.T) {
+	mask, err := fieldmask_utils.MaskFromPathsFor((*testproto.UpdateUserRequest)(nil), []string{"user.male_name"})
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskFromString("User{MaleName}"), mask)
+}
+
+func TestMaskFromProtoFieldMaskFor_Failure(t *testing.T) {
+	testCases := []struct {
+		paths         []string
+		expectedError string
+	}{
+		{[]string{"id", "usrname"}, `path "usrname": field "usrname" does not exist in message User`},
+		{[]string{"avatar.url"}, `path "avatar.url": field "url" does not exist in message Image`},
+		{[]string{"Username"}, `path "Username": field "Username" does not exist in message User`},
+		{[]string{"id.foo"}, `path "id.foo": "id" is not a message and has no fields`},
+		{[]string{"avatar."}, `invalid path "avatar.": syntax error at offset 7: expected field name, got end of input`},
+		{[]string{"avatar.*"}, `path "avatar.*": wildcard is only allowed after a repeated or map field`},
+		{[]string{"`id`"}, "path \"`id`\": quoted segment \"id\" is only allowed as a map key"},
+		{[]string{"images_by_size.*.*"}, `invalid path "images_by_size.*.*": syntax error at offset 17: expected field name, got '*'`},
+		{[]string{"meta.color.red"}, `path "meta.color.red": "color" is not a message and has no fields`},
+		{[]string{"images_by_size.small.url"}, `path "images_by_size.small.url": field "url" does not exist in message Image`},
+		{[]string{"extra_user.id"}, `path "extra_user.id": sub-paths of google.protobuf.Any field "extra_user" are not supported`},
+		{[]string{"details.*.type_url"}, `path "details.*.type_url": sub-paths of google.protobuf.Any field "details" are not supported`},
+	}
+	for _, testCase := range testCases {
+		_, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, &field_mask.FieldMask{Paths: testCase.paths})
+		assert.EqualError(t, err, testCase.expectedError)
+	}
+}
+
+func TestStructToStruct_MaskFromProtoFieldMaskFor(t *testing.T) {
+	mask, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, &field_mask.FieldMask{
+		Paths: []string{"id", "male_name", "avatar.original_url"},
+	})
+	require.NoError(t, err)
+	userDst := &testproto.User{}
+	err = fieldmask_utils.StructToStruct(mask, testUserFull, userDst)
+	require.NoError(t, err)
+	assert.Equal(t, testUserFull.Id, userDst.Id)
+	assert.Equal(t, testUserFull.GetMaleName(), userDst.GetMaleName())
+	assert.Equal(t, testUserFull.Avatar.OriginalUrl, userDst.Avatar.OriginalUrl)
+	assert.Equal(t, "", userDst.Avatar.ResizedUrl)
+}
+
+func TestMaskFromProtoFieldMask_SameAsFor(t *testing.T) {
+	fm := &field_mask.FieldMask{Paths: []string{"id", "male_name", "avatar.original_url"}}
+	goNames := map[string]string{"id": "Id", "male_name": "MaleName", "avatar": "Avatar", "original_url": "OriginalUrl"}
+	mask, err := fieldmask_utils.MaskFromProtoFieldMask(fm, func(s string) string { return goNames[s] })
+	require.NoError(t, err)
+	maskFor, err := fieldmask_utils.MaskFromProtoFieldMaskFor(&testproto.User{}, fm)
+	require.NoError(t, err)
+	assert.Equal(t, maskFor, mask)
+}
@@ -1,6 +1,8 @@
 package fieldmask_utils_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,6 +17,47 @@ func TestMask_String(t *testing.T) {
 	assert.Equal(t, "a{b{c}}", mask.String())
 }
 
+func TestMask_String_Canonical(t *testing.T) {
+	testCases := []struct {
+		mask     fieldmask_utils.Mask
+		expected string
+	}{
+		{fieldmask_utils.MaskFromString("c,b{z,y{x}},a"), "a,b{y{x},z},c"},
+		{fieldmask_utils.MaskFromString("*{b},a"), "*{b},a"},
+		{fieldmask_utils.Mask{"meta": fieldmask_utils.Mask{
+			"a,b":  fieldmask_utils.Mask{},
+			"{c}":  fieldmask_utils.Mask{"d": fieldmask_utils.Mask{}},
+			"e f":  nil,
+			"`g`":  fieldmask_utils.Mask{},
+			"h`i":  fieldmask_utils.Mask{},
+			"":     fieldmask_utils.Mask{},
+			"ключ": fieldmask_utils.Mask{},
+		}}, "meta{``,```g```,`a,b`,`e f`,h`i,`{c}`{d},ключ}"},
+		{fieldmask_utils.Mask{}, ""},
+	}
+	for _, testCase := range testCases {
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, testCase.expected, testCase.mask.String())
+		}
+		mask, err := fieldmask_utils.ParseMask(testCase.mask.String(), func(s string) string { return s })
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, mask.String())
+	}
+}
+
+func TestMask_String_RoundTrip(t *testing.T) {
+	mask := fieldmask_utils.Mask{
+		"a": fieldmask_utils.Mask{
+			"b,c": fieldmask_utils.Mask{"}": fieldmask_utils.Mask{}},
+			"`":   fieldmask_utils.Mask{},
+		},
+		"d": fieldmask_utils.Mask{},
+	}
+	parsed, err := fieldmask_utils.ParseMask(mask.String(), func(s string) string { return s })
+	require.NoError(t, err)
+	assert.Equal(t, mask, parsed)
+}
+
 func TestMaskInverse_String(t *testing.T) {
 	mask := fieldmask_utils.MaskInverseFromString("a{b{c}}")
 	assert.Equal(t, "a{b{c}}", mask.String())
@@ -78,6 +121,99 @@ func TestMaskFromProtoFieldMask_Failure(t *testing.T) {
 	}
 }
 
+func TestMaskFromPaths_QuotedAndWildcard(t *testing.T) {
+	mask, err := fieldmask_utils.MaskFromPaths([]string{
+		"meta.`color.dark`",
+		"meta.`with ``backticks```",
+		"meta.`key`",
+		"images.*.url",
+		"*",
+	}, strings.ToUpper)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{
+		"META": fieldmask_utils.Mask{
+			"color.dark":       fieldmask_utils.Mask{},
+			"with `backticks`": fieldmask_utils.Mask{},
+			"key":              fieldmask_utils.Mask{},
+		},
+		"IMAGES": fieldmask_utils.Mask{
+			"*": fieldmask_utils.Mask{"URL": fieldmask_utils.Mask{}},
+		},
+		"*": fieldmask_utils.Mask{},
+	}, mask)
+}
+
+func TestMaskFromPaths_InvalidPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		offset   int
+		expected string
+	}{
+		{"", 0, "field name"},
+		{"a..b", 2, "field name"},
+		{"a.", 2, "field name"},
+		{"*.a", 0, "field name"},
+		{"a.*.*", 4, "field name"},
+		{"a*", 1, "'.' or end of path"},
+		{"a.`b`c", 5, "'.' or end of path"},
+		{"a.`b", 4, "'`'"},
+		{"a.`b``", 6, "'`'"},
+	}
+	for _, testCase := range testCases {
+		mask, err := fieldmask_utils.MaskFromPaths([]string{"x", testCase.path}, func(s string) string { return s })
+		assert.Nil(t, mask, testCase.path)
+		var parseErr *fieldmask_utils.ParseError
+		require.True(t, errors.As(err, &parseErr), testCase.path)
+		assert.Equal(t, testCase.path, parseErr.Input)
+		assert.Equal(t, testCase.offset, parseErr.Offset, testCase.path)
+		assert.Equal(t, testCase.expected, parseErr.Expected, testCase.path)
+	}
+
+	_, err := fieldmask_utils.MaskFromPaths([]string{"a.*.*"}, func(s string) string { return s })
+	assert.EqualError(t, err, `invalid path "a.*.*": syntax error at offset 4: expected field name, got '*'`)
+}
+
+func TestMask_Paths(t *testing.T) {
+	testCases := []struct {
+		mask          fieldmask_utils.Mask
+		expectedPaths []string
+	}{
+		{fieldmask_utils.MaskFromString("d,a{c,b}"), []string{"a.b", "a.c", "d"}},
+		{fieldmask_utils.MaskFromString("a{b{c{d}}},e"), []string{"a.b.c.d", "e"}},
+		{fieldmask_utils.MaskFromString("a{*{b}},c{*}"), []string{"a.*.b", "c.*"}},
+		{fieldmask_utils.Mask{"meta": fieldmask_utils.Mask{
+			"color.dark":    fieldmask_utils.Mask{},
+			"with `quotes`": fieldmask_utils.Mask{},
+			"":              fieldmask_utils.Mask{},
+			"имя_1":         fieldmask_utils.Mask{},
+		}}, []string{"meta.``", "meta.`color.dark`", "meta.`with ``quotes```", "meta.имя_1"}},
+		{fieldmask_utils.Mask{"a": nil}, []string{"a"}},
+		{fieldmask_utils.Mask{}, nil},
+	}
+	for _, testCase := range testCases {
+		paths, err := testCase.mask.Paths(nil)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expectedPaths, paths, testCase.mask)
+
+		if len(testCase.expectedPaths) > 0 {
+			mask, err := fieldmask_utils.MaskFromPaths(paths, func(s string) string { return s })
+			require.NoError(t, err)
+			paths, err = mask.Paths(nil)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedPaths, paths)
+		}
+	}
+}
+
+func TestMask_ToProtoFieldMask(t *testing.T) {
+	mask := fieldmask_utils.MaskFromString("Avatar{OriginalUrl},Id,ImagesBySize{*{ResizedUrl}}")
+	fieldMask, err := mask.ToProtoFieldMask(strings.ToLower)
+	require.NoError(t, err)
+	assert.Equal(t, &field_mask.FieldMask{
+		Paths: []string{"avatar.originalurl", "id", "imagesbysize.*.resizedurl"},
+	}, fieldMask)
+}
+
 func TestMaskFromString(t *testing.T) {
 	testCases := []struct {
 		input        string
@@ -0,0 +1,152 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// MaskFromNonZero returns a Mask of all the fields that are set in the given struct (or a pointer to a struct), which can
+// be used with StructToStruct to implement merge-patch semantics. A field is set if it is:
+//   - a non-nil pointer or interface: the fields set in the struct it points to are selected, or the whole field if
+//     none of them is set;
+//   - a non-empty slice or map: the whole field is selected;
+//   - a struct with some of its fields set, or a non-zero value of any other type (including structs without exported
+//     fields like time.Time);
+//   - a set member of a protobuf oneof: the member is selected by its own name, e.g. "MaleName".
+//
+// Fields of generated protobuf messages are checked with protoreflect, so that proto3 `optional` fields are selected if
+// they are present even if they hold a zero value. Values of google.protobuf.Any fields are selected as a whole.
+// Only the fields selected by the filter set with the WithFilter option are checked (all the fields by default).
+// Field names are resolved according to the WithSrcTag option.
+// Note that the result is an empty Mask if none of the fields is set, which copies all the fields when used with
+// StructToStruct: check it with IsEmpty first.
+func MaskFromNonZero(v interface{}, opts ...Option) Mask {
+	userOptions := newDefaultOptions()
+	for _, o := range opts {
+		o(userOptions)
+	}
+	filter := userOptions.Filter
+	if filter == nil {
+		filter = Mask{}
+	}
+	mask, _ := nonZero(filter, reflect.ValueOf(v), userOptions)
+	if mask == nil {
+		return Mask{}
+	}
+	return mask
+}
+
+// nonZero returns the Mask of the set fields of the given value and true if the value is set. An empty Mask is returned
+// if the value is set as a whole.
+func nonZero(filter FieldFilter, v reflect.Value, userOptions *options) (Mask, bool) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, false
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false
+		}
+		if isGeneratedMessage(v.Type()) {
+			if v.Type() == reflect.TypeOf((*anypb.Any)(nil)) {
+				return Mask{}, true
+			}
+			return nonZeroMessage(filter, v.Interface().(proto.Message), userOptions), true
+		}
+		if mask, ok := nonZero(filter, v.Elem(), userOptions); ok {
+			return mask, true
+		}
+		return Mask{}, true
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		if mask, ok := nonZero(filter, v.Elem(), userOptions); ok {
+			return mask, true
+		}
+		return Mask{}, true
+
+	case reflect.Slice, reflect.Map:
+		return Mask{}, v.Len() > 0
+
+	case reflect.Struct:
+		if !hasExportedFields(v.Type()) {
+			return Mask{}, !v.IsZero()
+		}
+		mask := Mask{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !isExported(field) {
+				continue
+			}
+			name := fieldName(userOptions.SrcTag, field)
+			if isOneof(field) {
+				memberField, ok := oneofCase(v.Field(i))
+				if !ok {
+					continue
+				}
+				memberName := fieldName(userOptions.SrcTag, memberField)
+				subFilter, ok := oneofMemberFilter(filter, name, memberName)
+				if !ok {
+					continue
+				}
+				sub, set := nonZero(subFilter, v.Field(i).Elem().Elem().Field(0), userOptions)
+				if !set {
+					// A oneof member is set even if it holds a zero value.
+					sub = Mask{}
+				}
+				mask[memberName] = sub
+				continue
+			}
+			subFilter, ok := filter.Filter(name)
+			if !ok {
+				continue
+			}
+			if sub, set := nonZero(subFilter, v.Field(i), userOptions); set {
+				mask[name] = sub
+			}
+		}
+		return mask, !mask.IsEmpty()
+
+	default:
+		return Mask{}, !v.IsZero()
+	}
+}
+
+// nonZeroMessage returns the Mask of the fields present in the given protobuf message.
+func nonZeroMessage(filter FieldFilter, msg proto.Message, userOptions *options) Mask {
+	mask := Mask{}
+	msgType := reflect.TypeOf(msg)
+	structValue := reflect.ValueOf(msg).Elem()
+	msg.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		field, err := protoGoFieldByName(msgType, string(fd.Name()))
+		if err != nil || field == nil {
+			return true
+		}
+		name := fieldName(userOptions.SrcTag, field.field)
+		var subFilter FieldFilter
+		var ok bool
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			oneofField, _ := structFieldByTag(structValue.Type(), "protobuf_oneof", string(od.Name()))
+			subFilter, ok = oneofMemberFilter(filter, fieldName(userOptions.SrcTag, oneofField), name)
+		} else {
+			subFilter, ok = filter.Filter(name)
+		}
+		if !ok {
+			return true
+		}
+		sub := Mask{}
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && fd.Message().FullName() != anyMessageName {
+			if m, set := nonZero(subFilter, reflect.ValueOf(value.Message().Interface()), userOptions); set {
+				sub = m
+			}
+		}
+		mask[name] = sub
+		return true
+	})
+	return mask
+}
@@ -0,0 +1,122 @@
+package fieldmask_utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestMaskFromNonZero(t *testing.T) {
+	type B struct {
+		Field1 string `json:"field_1"`
+		Field2 int    `json:"field_2"`
+	}
+	type A struct {
+		Field1 string            `json:"field_1"`
+		Field2 *B                `json:"field_2"`
+		Field3 B                 `json:"field_3"`
+		Field4 []int             `json:"field_4"`
+		Field5 map[string]string `json:"field_5"`
+		Field6 interface{}       `json:"field_6"`
+		Field7 time.Time         `json:"field_7"`
+		Field8 *int              `json:"field_8"`
+		field9 int
+	}
+	zero := 0
+	testCases := []struct {
+		name         string
+		value        interface{}
+		expectedMask string
+	}{
+		{"zero", &A{}, ""},
+		{"nil", (*A)(nil), ""},
+		{"scalar", &A{Field1: "a"}, "Field1"},
+		{"nested pointer", &A{Field2: &B{Field2: 1}}, "Field2{Field2}"},
+		{"empty nested pointer", &A{Field2: &B{}}, "Field2"},
+		{"nested struct", A{Field3: B{Field1: "b"}}, "Field3{Field1}"},
+		{"empty list and map", &A{Field4: []int{}, Field5: map[string]string{}}, ""},
+		{"list and map", &A{Field4: []int{0}, Field5: map[string]string{"a": ""}}, "Field4,Field5"},
+		{"interface", &A{Field6: &B{Field1: "b"}}, "Field6{Field1}"},
+		{"time", &A{Field7: time.Now()}, "Field7"},
+		{"pointer to zero", &A{Field8: &zero}, "Field8"},
+		{"unexported", &A{field9: 1}, ""},
+	}
+	for _, testCase := range testCases {
+		mask := fieldmask_utils.MaskFromNonZero(testCase.value)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expectedMask), mask, testCase.name)
+	}
+
+	mask := fieldmask_utils.MaskFromNonZero(&A{Field1: "a", Field2: &B{Field2: 1}}, fieldmask_utils.WithSrcTag("json"))
+	assert.Equal(t, fieldmask_utils.MaskFromString("field_1,field_2{field_2}"), mask)
+
+	mask = fieldmask_utils.MaskFromNonZero(&A{Field1: "a", Field2: &B{Field1: "b", Field2: 1}},
+		fieldmask_utils.WithFilter(fieldmask_utils.MaskFromString("Field2{Field2}")))
+	assert.Equal(t, fieldmask_utils.MaskFromString("Field2{Field2}"), mask)
+
+	mask = fieldmask_utils.MaskFromNonZero(&A{Field1: "a"},
+		fieldmask_utils.WithFilter(fieldmask_utils.MaskFromString("Field2")))
+	assert.True(t, mask.IsEmpty())
+}
+
+func TestMaskFromNonZero_Proto(t *testing.T) {
+	extraUser, err := anypb.New(&testproto.User{Id: 1})
+	require.NoError(t, err)
+	testCases := []struct {
+		name         string
+		user         *testproto.User
+		expectedMask string
+	}{
+		{"empty", &testproto.User{}, ""},
+		{"scalars", &testproto.User{Id: 1, Role: testproto.Role_REGULAR}, "Id,Role"},
+		{"optional zero value", &testproto.User{Alias: proto.String("")}, "Alias"},
+		{"oneof zero value", &testproto.User{Name: &testproto.User_FemaleName{}}, "FemaleName"},
+		{"nested", &testproto.User{Avatar: &testproto.Image{ResizedUrl: "url"}}, "Avatar{ResizedUrl}"},
+		{"empty nested", &testproto.User{Avatar: &testproto.Image{}}, "Avatar"},
+		{"lists and maps", &testproto.User{
+			Friends:      []*testproto.User{{Id: 1}},
+			ImagesBySize: map[string]*testproto.Image{"small": {}},
+		}, "Friends,ImagesBySize"},
+		{"any", &testproto.User{ExtraUser: extraUser}, "ExtraUser"},
+	}
+	for _, testCase := range testCases {
+		mask := fieldmask_utils.MaskFromNonZero(testCase.user)
+		assert.Equal(t, fieldmask_utils.MaskFromString(testCase.expectedMask), mask, testCase.name)
+	}
+}
+
+func TestMaskFromNonZero_MergePatch(t *testing.T) {
+	patch := &testproto.User{
+		Username: "new username",
+		Alias:    proto.String(""),
+		Avatar:   &testproto.Image{ResizedUrl: "new resized url"},
+	}
+	dst := &testproto.User{
+		Id:       1,
+		Username: "username",
+		Alias:    proto.String("alias"),
+		Avatar:   &testproto.Image{OriginalUrl: "original url", ResizedUrl: "resized url"},
+	}
+	mask := fieldmask_utils.MaskFromNonZero(patch)
+	require.False(t, mask.IsEmpty())
+	require.NoError(t, fieldmask_utils.StructToStruct(mask, patch, dst))
+	assert.Equal(t, &testproto.User{
+		Id:       1,
+		Username: "new username",
+		Alias:    proto.String(""),
+		Avatar:   &testproto.Image{OriginalUrl: "original url", ResizedUrl: "new resized url"},
+	}, dst)
+}
+
+func TestMaskFromNonZero_EmptyPatch(t *testing.T) {
+	assert.True(t, fieldmask_utils.MaskFromNonZero(&testproto.User{}).IsEmpty())
+
+	var nilPatch *testproto.User
+	assert.True(t, fieldmask_utils.MaskFromNonZero(nilPatch).IsEmpty())
+}
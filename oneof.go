@@ -0,0 +1,152 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Protobuf oneof fields are generated as an interface field (e.g. User.Name) holding a pointer to a wrapper struct
+// with a single field (e.g. User_MaleName.MaleName). Masks may address the oneof members either through the oneof
+// field ("Name{MaleName}") or directly by the member name ("MaleName") the same way a fieldmaskpb.FieldMask does.
+// The functions below handle the latter case.
+
+// isOneof returns true if the given struct field is a protobuf oneof field.
+func isOneof(f reflect.StructField) bool {
+	_, ok := f.Tag.Lookup("protobuf_oneof")
+	return ok && f.Type.Kind() == reflect.Interface
+}
+
+// oneofCase returns the field of the oneof wrapper struct held by the given oneof interface value.
+// Result is false if the oneof is not set.
+func oneofCase(v reflect.Value) (reflect.StructField, bool) {
+	if !v.IsValid() || v.Kind() != reflect.Interface || v.IsNil() {
+		return reflect.StructField{}, false
+	}
+	wrapper := v.Elem()
+	if wrapper.Kind() != reflect.Ptr || wrapper.IsNil() || wrapper.Elem().Kind() != reflect.Struct ||
+		wrapper.Elem().NumField() != 1 {
+		return reflect.StructField{}, false
+	}
+	return wrapper.Elem().Type().Field(0), true
+}
+
+// mentionsField returns true if the given filter explicitly has an entry for the given field name.
+func mentionsField(filter FieldFilter, fieldName string) bool {
+	container, ok := filter.(FieldFilterContainer)
+	if !ok {
+		return false
+	}
+	_, ok = container.Get(fieldName)
+	return ok
+}
+
+// oneofMemberFilter returns the FieldFilter for the given oneof member. If the member is not mentioned in the filter
+// directly, the filter for the oneof field is used instead.
+func oneofMemberFilter(filter FieldFilter, oneofName, memberName string) (FieldFilter, bool) {
+	if mentionsField(filter, memberName) {
+		return filter.Filter(memberName)
+	}
+	subFilter, ok := filter.Filter(oneofName)
+	if !ok || subFilter == nil {
+		return subFilter, false
+	}
+	return subFilter.Filter(memberName)
+}
+
+// oneofToOneof copies the oneof `src` interface value to `dst` if the filter mentions any of their members directly.
+// A selected member is copied to `dst` if it is set in `src`, otherwise it is cleared in `dst`.
+// Result is false if none of the members is mentioned, in which case the field is supposed to be copied as a regular
+// interface field.
+func oneofToOneof(filter FieldFilter, oneofName string, src, dst reflect.Value, userOptions *options) (bool, error) {
+	if !dst.IsValid() || dst.Kind() != reflect.Interface || !dst.CanSet() {
+		return false, nil
+	}
+	srcCase, srcOk := oneofCase(src)
+	dstCase, dstOk := oneofCase(dst)
+	srcMember := fieldName(userOptions.SrcTag, srcCase)
+	dstMember := fieldName(userOptions.SrcTag, dstCase)
+	if !(srcOk && mentionsField(filter, srcMember)) && !(dstOk && mentionsField(filter, dstMember)) {
+		return false, nil
+	}
+
+	if srcOk {
+		if subFilter, ok := oneofMemberFilter(filter, oneofName, srcMember); ok {
+			srcWrapper := src.Elem()
+			if !dstOk || dst.Elem().Type() != srcWrapper.Type() {
+				if !srcWrapper.Type().AssignableTo(dst.Type()) {
+					return true, errors.Errorf("oneof member %s is not assignable to %s", srcWrapper.Type(), dst.Type())
+				}
+				dst.Set(reflect.New(srcWrapper.Type().Elem()))
+			}
+			srcItem, dstItem := srcWrapper.Elem().Field(0), dst.Elem().Elem().Field(0)
+			userOptions.pushPath(srcMember)
+			defer userOptions.popPath()
+			return true, structToStruct(subFilter, &srcItem, &dstItem, userOptions)
+		}
+	}
+
+	if dstOk && (!srcOk || dstMember != srcMember) {
+		if _, ok := oneofMemberFilter(filter, oneofName, dstMember); ok {
+			// The member is selected, but it is not set in src.
+			dst.Set(reflect.Zero(dst.Type()))
+		}
+	}
+	return true, nil
+}
+
+// oneofToMap copies the members of the given oneof field of the `src` struct to the `dst` map if the filter mentions
+// any of them directly. Every selected member is stored under its own key: the value is copied if the member is set in
+// `src`, otherwise the value is nil.
+// Result is false if none of the members is mentioned, in which case the field is supposed to be copied as a regular
+// struct field.
+func oneofToMap(filter FieldFilter, src, dst reflect.Value, field reflect.StructField, userOptions *options) (bool, error) {
+	srcValue := src.FieldByIndex(field.Index)
+	members := oneofMemberFields(src.Type(), field)
+	srcCase, srcOk := oneofCase(srcValue)
+	if srcOk && !hasStructField(members, srcCase.Name) {
+		members = append(members, srcCase)
+	}
+
+	mentioned := false
+	for _, member := range members {
+		if mentionsField(filter, fieldName(userOptions.SrcTag, member)) {
+			mentioned = true
+			break
+		}
+	}
+	if !mentioned {
+		return false, nil
+	}
+
+	oneofName := fieldName(userOptions.SrcTag, field)
+	for _, member := range members {
+		srcName := fieldName(userOptions.SrcTag, member)
+		subFilter, ok := oneofMemberFilter(filter, oneofName, srcName)
+		if !ok {
+			continue
+		}
+		dstName := fieldName(userOptions.DstTag, member)
+		if !srcOk || srcCase.Name != member.Name {
+			dst.SetMapIndex(reflect.ValueOf(dstName), reflect.Zero(dst.Type().Elem()))
+			continue
+		}
+		userOptions.pushPath(srcName)
+		err := fieldToMap(filter, subFilter, src, dst, srcName, dstName, srcValue.Elem().Elem().Field(0), userOptions)
+		userOptions.popPath()
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// hasStructField returns true if the given fields contain a field with the given name.
+func hasStructField(fields []reflect.StructField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+package fieldmask_utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// ParseError is returned when a string representation of a FieldFilter or a field path is malformed.
+type ParseError struct {
+	// Input is the string that was being parsed.
+	Input string
+	// Offset is the byte offset in Input where the error was detected.
+	Offset int
+	// Expected describes the token that was expected at Offset.
+	Expected string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: expected %s, got %s", e.Offset, e.Expected, e.found())
+}
+
+// found returns a human readable representation of the token found at the error offset.
+func (e *ParseError) found() string {
+	if e.Offset >= len(e.Input) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRuneInString(e.Input[e.Offset:])
+	return fmt.Sprintf("%q", r)
+}
+
+// ParseMask creates a new Mask from a string like "a,b{c,d{e}}".
+// See ParseFieldFilter for details.
+func ParseMask(input string, naming func(string) string, opts ...ParseOption) (Mask, error) {
+	mask, err := ParseFieldFilter(input, naming, func() FieldFilterContainer {
+		return make(Mask)
+	}, opts...)
+	if mask != nil {
+		return mask.(Mask), err
+	}
+	return nil, err
+}
+
+// ParseMaskInverse creates a new MaskInverse from a string like "a,b{c,d{e}}".
+// See ParseFieldFilter for details.
+func ParseMaskInverse(input string, naming func(string) string, opts ...ParseOption) (MaskInverse, error) {
+	mask, err := ParseFieldFilter(input, naming, func() FieldFilterContainer {
+		return make(MaskInverse)
+	}, opts...)
+	if mask != nil {
+		return mask.(MaskInverse), err
+	}
+	return nil, err
+}
+
+// ParseFieldFilter creates a new FieldFilterContainer from its string representation like "a,b{c,d{e}}".
+// Field names are separated by commas, nested filters are enclosed in curly braces and white spaces between the
+// tokens are ignored. An empty (or blank) string results in an empty FieldFilterContainer.
+// Every field name is passed through the naming function before it is added to the filter, except the names enclosed in
+// backticks: they are used as is and may contain delimiters, e.g. "meta{`a,b`}" (a backtick is escaped by doubling it).
+// Repeated field names are merged the same way FieldFilterFromPaths does: "a,a{b}" is the same as "a{b}".
+// A *ParseError is returned if the input is malformed, e.g. has unbalanced braces, empty field names or trailing
+// characters.
+// The size of the result can be limited with the ParseOption functions the same way MaskFromPathsWithOptions does: every
+// field name without a nested list ends a path, e.g. "a,b{c,d}" has 3 paths of at most 2 segments and 4 nodes. The
+// limits are checked while the input is parsed and an error matching ErrMaskTooLarge is returned as soon as any of them
+// is exceeded.
+func ParseFieldFilter(input string, naming func(string) string, filter func() FieldFilterContainer, opts ...ParseOption) (FieldFilterContainer, error) {
+	p := &filterParser{input: input, naming: naming, filter: filter, limiter: newPathsLimiter(opts)}
+	root := filter()
+	p.skipSpaces()
+	if p.eof() {
+		return root, nil
+	}
+	if err := p.parseList(root); err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("',' or end of input")
+	}
+	return root, nil
+}
+
+// filterParser is a recursive descent parser for the FieldFilter string representation.
+type filterParser struct {
+	input   string
+	pos     int
+	naming  func(string) string
+	filter  func() FieldFilterContainer
+	limiter *pathsLimiter
+	// depth is the number of the lists being parsed, i.e. the number of segments of the paths in the current list.
+	depth int
+}
+
+// parseList parses a comma separated list of fields (each of them with an optional nested list) into the given
+// container. It stops at the first character that can not continue the list.
+func (p *filterParser) parseList(container FieldFilterContainer) error {
+	p.depth++
+	defer func() { p.depth-- }()
+	if err := p.limiter.checkDepth(p.depth); err != nil {
+		return err
+	}
+	for {
+		name, err := p.parseName()
+		if err != nil {
+			return err
+		}
+		subFilter, err := p.limiter.subContainer(container, name, p.filter)
+		if err != nil {
+			return err
+		}
+		p.skipSpaces()
+		if p.peek() == '{' {
+			p.pos++
+			p.skipSpaces()
+			if err := p.parseList(subFilter); err != nil {
+				return err
+			}
+			if p.peek() != '}' {
+				return p.errorf("',' or '}'")
+			}
+			p.pos++
+			p.skipSpaces()
+		} else if err := p.limiter.addPath(); err != nil {
+			return err
+		}
+		if p.peek() != ',' {
+			return nil
+		}
+		p.pos++
+		p.skipSpaces()
+	}
+}
+
+// parseName parses a non-empty field name and returns it after applying the naming function.
+// A name enclosed in backticks is returned as is.
+func (p *filterParser) parseName() (string, error) {
+	if p.peek() == '`' {
+		return p.parseQuotedName()
+	}
+	start := p.pos
+	for !p.eof() && !isFilterDelimiter(p.input[p.pos]) {
+		p.pos++
+		if err := p.limiter.checkSegmentLength(p.pos - start); err != nil {
+			return "", err
+		}
+	}
+	if p.pos == start {
+		return "", p.errorf("field name")
+	}
+	return p.naming(p.input[start:p.pos]), nil
+}
+
+// parseQuotedName parses a name enclosed in backticks. A backtick inside the name is escaped by doubling it.
+func (p *filterParser) parseQuotedName() (string, error) {
+	var name strings.Builder
+	p.pos++
+	for !p.eof() {
+		if err := p.limiter.checkSegmentLength(name.Len()); err != nil {
+			return "", err
+		}
+		if p.input[p.pos] != '`' {
+			name.WriteByte(p.input[p.pos])
+			p.pos++
+			continue
+		}
+		p.pos++
+		if p.peek() != '`' {
+			return name.String(), nil
+		}
+		// Escaped backtick.
+		name.WriteByte('`')
+		p.pos++
+	}
+	return "", p.errorf("'`'")
+}
+
+func (p *filterParser) skipSpaces() {
+	for !p.eof() && isFilterSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *filterParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek returns the current byte or 0 if the end of input is reached.
+func (p *filterParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *filterParser) errorf(expected string) error {
+	return &ParseError{Input: p.input, Offset: p.pos, Expected: expected}
+}
+
+func isFilterSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '\r'
+}
+
+func isFilterDelimiter(c byte) bool {
+	return c == ',' || c == '{' || c == '}' || isFilterSpace(c)
+}
+
+// pathSegment is a single segment of a field path.
+type pathSegment struct {
+	name string
+	// quoted is true if the segment is enclosed in backticks. Quoted segments are map keys used as is.
+	quoted bool
+}
+
+// isWildcard returns true if the segment is an unquoted Wildcard.
+func (s pathSegment) isWildcard() bool {
+	return !s.quoted && s.name == Wildcard
+}
+
+// splitPath splits the given field path into segments according to the AIP-161 syntax: segments are separated by dots,
+// a segment enclosed in backticks may contain any characters (a backtick is escaped by doubling it) and "*" is a
+// wildcard. A wildcard may not be followed by another wildcard and may start a path only if it is the entire path.
+// The depth of the path and the length of its segments are checked by the given limiter while the path is scanned.
+func splitPath(path string, limiter *pathsLimiter) ([]pathSegment, error) {
+	var segments []pathSegment
+	pos := 0
+	for {
+		if err := limiter.checkDepth(len(segments) + 1); err != nil {
+			return nil, err
+		}
+		var segment pathSegment
+		switch {
+		case pos < len(path) && path[pos] == '`':
+			var name strings.Builder
+			pos++
+			closed := false
+			for pos < len(path) && !closed {
+				switch {
+				case path[pos] != '`':
+					name.WriteByte(path[pos])
+					pos++
+				case pos+1 < len(path) && path[pos+1] == '`':
+					// Escaped backtick.
+					name.WriteByte('`')
+					pos += 2
+				default:
+					pos++
+					closed = true
+				}
+				if err := limiter.checkSegmentLength(name.Len()); err != nil {
+					return nil, err
+				}
+			}
+			if !closed {
+				return nil, &ParseError{Input: path, Offset: pos, Expected: "'`'"}
+			}
+			segment = pathSegment{name: name.String(), quoted: true}
+
+		case pos < len(path) && path[pos] == '*':
+			if (len(segments) == 0 && pos+1 < len(path)) || (len(segments) > 0 && segments[len(segments)-1].isWildcard()) {
+				return nil, &ParseError{Input: path, Offset: pos, Expected: "field name"}
+			}
+			segment = pathSegment{name: Wildcard}
+			pos++
+
+		default:
+			start := pos
+			for pos < len(path) && path[pos] != '.' && path[pos] != '`' && path[pos] != '*' {
+				pos++
+				if err := limiter.checkSegmentLength(pos - start); err != nil {
+					return nil, err
+				}
+			}
+			if pos == start {
+				return nil, &ParseError{Input: path, Offset: pos, Expected: "field name"}
+			}
+			segment = pathSegment{name: path[start:pos]}
+		}
+		segments = append(segments, segment)
+		if pos == len(path) {
+			return segments, nil
+		}
+		if path[pos] != '.' {
+			return nil, &ParseError{Input: path, Offset: pos, Expected: "'.' or end of path"}
+		}
+		pos++
+	}
+}
+
+// pathError annotates the given error of splitPath with the path. The errors of the limits are returned as is, so that
+// an oversized path is not copied to the error message.
+func pathError(path string, err error) error {
+	if errors.Is(err, ErrMaskTooLarge) {
+		return err
+	}
+	return errors.Wrapf(err, "invalid path %q", path)
+}
+
+// quoteFilterName encloses the given field name in backticks (escaping the backticks inside) if it can not be parsed
+// by ParseFieldFilter as is: it is empty, starts with a backtick or contains delimiters.
+func quoteFilterName(name string) string {
+	if name != "" && name[0] != '`' && strings.IndexFunc(name, func(r rune) bool {
+		return r < utf8.RuneSelf && isFilterDelimiter(byte(r))
+	}) < 0 {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// formatPathSegment returns the given field name as a segment of a field path: the Wildcard is used as is, other names
+// are quoted with quotePathSegment.
+func formatPathSegment(fieldName string) string {
+	if fieldName == Wildcard {
+		return fieldName
+	}
+	return quotePathSegment(fieldName)
+}
+
+// quotePathSegment encloses the given path segment in backticks (escaping the backticks inside) unless it consists of
+// letters, digits and underscores only.
+func quotePathSegment(segment string) string {
+	plain := segment != ""
+	for _, r := range segment {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return segment
+	}
+	return "`" + strings.ReplaceAll(segment, "`", "``") + "`"
+}
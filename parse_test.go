@@ -0,0 +1,128 @@
+package fieldmask_utils_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+)
+
+func TestParseMask_Success(t *testing.T) {
+	eye := func(s string) string { return s }
+	testCases := []struct {
+		input        string
+		expectedMask fieldmask_utils.Mask
+	}{
+		{"", fieldmask_utils.Mask{}},
+		{" \n\t", fieldmask_utils.Mask{}},
+		{"foo", fieldmask_utils.Mask{"foo": fieldmask_utils.Mask{}}},
+		{" foo , bar ", fieldmask_utils.Mask{
+			"foo": fieldmask_utils.Mask{},
+			"bar": fieldmask_utils.Mask{},
+		}},
+		{"a,b{c,d{e}}", fieldmask_utils.Mask{
+			"a": fieldmask_utils.Mask{},
+			"b": fieldmask_utils.Mask{
+				"c": fieldmask_utils.Mask{},
+				"d": fieldmask_utils.Mask{
+					"e": fieldmask_utils.Mask{},
+				},
+			},
+		}},
+		{"a { b } , c", fieldmask_utils.Mask{
+			"a": fieldmask_utils.Mask{"b": fieldmask_utils.Mask{}},
+			"c": fieldmask_utils.Mask{},
+		}},
+		{"a,a{b},a{c}", fieldmask_utils.Mask{
+			"a": fieldmask_utils.Mask{
+				"b": fieldmask_utils.Mask{},
+				"c": fieldmask_utils.Mask{},
+			},
+		}},
+		{"имя{поле}", fieldmask_utils.Mask{
+			"имя": fieldmask_utils.Mask{"поле": fieldmask_utils.Mask{}},
+		}},
+		{"meta{`a, b`,`{c}`{d}, `e``f` }", fieldmask_utils.Mask{
+			"meta": fieldmask_utils.Mask{
+				"a, b": fieldmask_utils.Mask{},
+				"{c}":  fieldmask_utils.Mask{"d": fieldmask_utils.Mask{}},
+				"e`f":  fieldmask_utils.Mask{},
+			},
+		}},
+		{"``", fieldmask_utils.Mask{"": fieldmask_utils.Mask{}}},
+	}
+	for _, testCase := range testCases {
+		mask, err := fieldmask_utils.ParseMask(testCase.input, eye)
+		require.NoError(t, err, testCase.input)
+		assert.Equal(t, testCase.expectedMask, mask, testCase.input)
+	}
+}
+
+func TestParseMask_Naming(t *testing.T) {
+	mask, err := fieldmask_utils.ParseMask("id,avatar{original_url},meta{`key`}", strings.ToUpper)
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.Mask{
+		"ID":     fieldmask_utils.Mask{},
+		"AVATAR": fieldmask_utils.Mask{"ORIGINAL_URL": fieldmask_utils.Mask{}},
+		"META":   fieldmask_utils.Mask{"key": fieldmask_utils.Mask{}},
+	}, mask)
+}
+
+func TestParseMaskInverse_Success(t *testing.T) {
+	mask, err := fieldmask_utils.ParseMaskInverse("a,b{c}", func(s string) string { return s })
+	require.NoError(t, err)
+	assert.Equal(t, fieldmask_utils.MaskInverse{
+		"a": fieldmask_utils.MaskInverse{},
+		"b": fieldmask_utils.MaskInverse{"c": fieldmask_utils.MaskInverse{}},
+	}, mask)
+}
+
+func TestParseMask_Failure(t *testing.T) {
+	testCases := []struct {
+		input    string
+		offset   int
+		expected string
+	}{
+		{",", 0, "field name"},
+		{"a,,b", 2, "field name"},
+		{"a,", 2, "field name"},
+		{"a}", 1, "',' or end of input"},
+		{"a{b}}", 4, "',' or end of input"},
+		{"a{b", 3, "',' or '}'"},
+		{"a{b{c}", 6, "',' or '}'"},
+		{"a{}", 2, "field name"},
+		{"{a}", 0, "field name"},
+		{"a b", 2, "',' or end of input"},
+		{"a{b c}", 4, "',' or '}'"},
+		{"a{`b}", 5, "'`'"},
+		{"`a`b", 3, "',' or end of input"},
+	}
+	for _, testCase := range testCases {
+		mask, err := fieldmask_utils.ParseMask(testCase.input, func(s string) string { return s })
+		assert.Nil(t, mask, testCase.input)
+		var parseErr *fieldmask_utils.ParseError
+		require.True(t, errors.As(err, &parseErr), testCase.input)
+		assert.Equal(t, testCase.input, parseErr.Input)
+		assert.Equal(t, testCase.offset, parseErr.Offset, testCase.input)
+		assert.Equal(t, testCase.expected, parseErr.Expected, testCase.input)
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	_, err := fieldmask_utils.ParseMask("a{b}}", func(s string) string { return s })
+	assert.EqualError(t, err, "syntax error at offset 4: expected ',' or end of input, got '}'")
+	_, err = fieldmask_utils.ParseMask("a{b", func(s string) string { return s })
+	assert.EqualError(t, err, "syntax error at offset 3: expected ',' or '}', got end of input")
+}
+
+func TestMaskFromString_Lenient(t *testing.T) {
+	// MaskFromString accepts the input that ParseMask rejects.
+	assert.Equal(t, fieldmask_utils.Mask{"a": fieldmask_utils.Mask{}, "b": fieldmask_utils.Mask{}},
+		fieldmask_utils.MaskFromString("a,,b"))
+	assert.Equal(t, fieldmask_utils.Mask{"ab": fieldmask_utils.Mask{}}, fieldmask_utils.MaskFromString("a b"))
+	assert.Equal(t, fieldmask_utils.Mask{"a": fieldmask_utils.Mask{}}, fieldmask_utils.MaskFromString("a{}"))
+}
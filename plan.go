@@ -0,0 +1,293 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// copyField describes how a field of a src struct is copied to a dst struct of a particular type.
+type copyField struct {
+	// index is the index of the field in the src struct.
+	index int
+	// srcName and dstName are the names of the field according to the SrcTag and DstTag options.
+	srcName, dstName string
+	// dstIndex is the index sequence of the field named dstName in the dst struct. Nil if there is no such field.
+	dstIndex []int
+	// oneof is true for protobuf oneof fields.
+	oneof bool
+	// members are the names of the members of a oneof field according to the SrcTag option.
+	members []string
+	// listKey is the key of the slice items declared by the field's tag, see WithListKey.
+	listKey string
+}
+
+// copyFieldsKey identifies the []copyField computed for a pair of struct types.
+type copyFieldsKey struct {
+	src, dst       reflect.Type
+	srcTag, dstTag string
+}
+
+// copyFieldsCache holds the []copyField for every copyFieldsKey seen so far. The number of entries is bounded by the
+// number of the struct types in the program (times the number of the tags in use).
+var copyFieldsCache sync.Map
+
+// structCopyFields returns the fields of the given src struct type resolved against the given dst struct type.
+func structCopyFields(srcType, dstType reflect.Type, userOptions *options) []copyField {
+	key := copyFieldsKey{src: srcType, dst: dstType, srcTag: userOptions.SrcTag, dstTag: userOptions.DstTag}
+	if fields, ok := copyFieldsCache.Load(key); ok {
+		return fields.([]copyField)
+	}
+	fields := make([]copyField, srcType.NumField())
+	for i := range fields {
+		f := srcType.Field(i)
+		field := copyField{
+			index:   i,
+			srcName: fieldName(userOptions.SrcTag, f),
+			dstName: fieldName(userOptions.DstTag, f),
+			oneof:   isOneof(f),
+			listKey: tagListKey(f),
+		}
+		if dstField, ok := dstType.FieldByName(field.dstName); ok {
+			field.dstIndex = dstField.Index
+		}
+		if field.oneof {
+			for _, member := range oneofMemberFields(srcType, f) {
+				field.members = append(field.members, fieldName(userOptions.SrcTag, member))
+			}
+		}
+		fields[i] = field
+	}
+	cached, _ := copyFieldsCache.LoadOrStore(key, fields)
+	return cached.([]copyField)
+}
+
+// copyFieldsFor returns the fields of the src struct which are supposed to be checked against the given filter: the
+// fields selected by the filter when the copy is planned (see Compile), all the fields otherwise.
+func copyFieldsFor(filter FieldFilter, srcType, dstType reflect.Type, userOptions *options) []copyField {
+	if userOptions.plan != nil {
+		if id, ok := filterID(filter); ok {
+			if fields, ok := userOptions.plan.fields[planKey{filter: id, src: srcType, dst: dstType}]; ok {
+				return fields
+			}
+		}
+	}
+	return structCopyFields(srcType, dstType, userOptions)
+}
+
+// fieldByIndex returns the nested field of the given struct by its index sequence. The zero Value is returned for a nil
+// index.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	if index == nil {
+		return reflect.Value{}
+	}
+	return v.FieldByIndex(index)
+}
+
+// Plan is a copy of the fields selected by a filter from a struct of one type to a struct of another type which is
+// prepared in advance by Compile, so that it can be performed many times without resolving the same field names and
+// tags over and over again. A Plan is safe for concurrent use.
+type Plan struct {
+	filter FieldFilter
+	// srcType is the struct type of src, dstType is the pointer type of dst.
+	srcType, dstType reflect.Type
+	options          *options
+	// fields holds the fields selected by the filters applied to the structs of the particular types.
+	fields map[planKey][]copyField
+}
+
+// planKey identifies the fields selected by a filter in a struct of the src type copied to a struct of the dst type.
+type planKey struct {
+	filter   uintptr
+	src, dst reflect.Type
+}
+
+// Compile prepares a copy of the fields selected by the filter from a struct of the srcType (or a pointer to it) to a
+// struct pointed to by a value of the dstType, see StructToStruct. The result can be reused for many Copy calls.
+// The fields of the structs which are known from the types are resolved in advance: the field names and tags of every
+// struct type, the fields the filter selects in each of them (Mask and MaskInverse filters only) and the compatibility
+// of the kinds of the src and dst fields. An error is returned if a selected field does not exist in dst or its kind is
+// not compatible with the src one and there are no converter hooks (see WithConverterHook).
+// The fields of the values of interfaces and google.protobuf.Any messages are only resolved during the copy.
+// Only the field resolution is done in advance: the values are still copied by the same reflection based code as in
+// StructToStruct, and the converter hooks are still tried during the copy for every value of an incompatible kind, as
+// they are selected by the values they return rather than by the types.
+// The filter must not be modified after the Plan is compiled.
+func Compile(filter FieldFilter, srcType, dstType reflect.Type, opts ...Option) (*Plan, error) {
+	userOptions := newDefaultOptions()
+	for _, o := range opts {
+		o(userOptions)
+	}
+	if userOptions.listRulesErr != nil {
+		return nil, userOptions.listRulesErr
+	}
+	if srcType == nil || dstType == nil {
+		return nil, errors.New("src and dst types must not be nil")
+	}
+	if dstType.Kind() != reflect.Ptr {
+		return nil, errors.Errorf("dst must be a pointer, %s given", dstType.Kind())
+	}
+	structType := srcType
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, errors.Errorf("src kind must be a struct, %s given", structType.Kind())
+	}
+	if dstType.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("dst kind must be a struct, %s given", dstType.Elem().Kind())
+	}
+	p := &Plan{
+		filter:  filter,
+		srcType: structType,
+		dstType: dstType,
+		options: userOptions,
+		fields:  make(map[planKey][]copyField),
+	}
+	if err := p.compile(filter, structType, dstType.Elem()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compile resolves the fields selected by the filter in the given types recursively.
+func (p *Plan) compile(filter FieldFilter, srcType, dstType reflect.Type) error {
+	if len(p.options.ConverterHooks) == 0 && !kindsCompatible(srcType, dstType) {
+		return errors.Errorf("src kind %s differs from dst kind %s", srcType.Kind(), dstType.Kind())
+	}
+	if filter == nil || filter.IsEmpty() {
+		// All the fields are copied.
+		return nil
+	}
+	if srcType.Kind() == reflect.Ptr {
+		if srcType == reflect.TypeOf((*anypb.Any)(nil)) {
+			// The type of the message is only known at runtime.
+			return nil
+		}
+		srcType = srcType.Elem()
+	}
+	if dstType.Kind() == reflect.Ptr {
+		dstType = dstType.Elem()
+	}
+	if srcType.Kind() != dstType.Kind() {
+		// Converted by the converter hooks at runtime.
+		return nil
+	}
+
+	switch srcType.Kind() {
+	case reflect.Struct:
+		id, ok := filterID(filter)
+		if !ok {
+			return nil
+		}
+		key := planKey{filter: id, src: srcType, dst: dstType}
+		if _, ok := p.fields[key]; ok {
+			return nil
+		}
+		// Mark the key as being compiled, so that recursive types are not compiled forever.
+		p.fields[key] = nil
+		var selected []copyField
+		for _, f := range structCopyFields(srcType, dstType, p.options) {
+			if f.oneof && (len(f.members) == 0 || srcType != dstType || mentionsAnyField(filter, f.members)) {
+				// The members are copied depending on which of them is set in src and dst.
+				selected = append(selected, f)
+				continue
+			}
+			subFilter, ok := filter.Filter(f.srcName)
+			if !ok {
+				continue
+			}
+			selected = append(selected, f)
+			srcField := srcType.Field(f.index)
+			if f.oneof || !isExported(srcField) {
+				continue
+			}
+			if f.dstIndex == nil || !isExported(dstType.FieldByIndex(f.dstIndex)) {
+				return errors.Errorf("Can't set a value on a destination field %s", f.dstName)
+			}
+			if err := p.compile(subFilter, srcField.Type, dstType.FieldByIndex(f.dstIndex).Type); err != nil {
+				return err
+			}
+		}
+		p.fields[key] = selected
+
+	case reflect.Slice, reflect.Array:
+		itemsFilter, ok := itemFilter(filter)
+		if !ok {
+			return nil
+		}
+		return p.compile(itemsFilter, srcType.Elem(), dstType.Elem())
+
+	case reflect.Map:
+		container, ok := filter.(FieldFilterContainer)
+		if !ok || reflect.TypeOf(container).Kind() != reflect.Map {
+			return nil
+		}
+		keys, err := filterFieldNames(container)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			subFilter, ok := mapKeyFilter(filter, key)
+			if !ok {
+				continue
+			}
+			if err := p.compile(subFilter, srcType.Elem(), dstType.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Copy copies `src` struct to `dst` struct the same way StructToStruct does with the compiled filter and options.
+// `src` must be a struct (or a pointer to a struct) of the compiled type, `dst` must be of the compiled type.
+func (p *Plan) Copy(src, dst interface{}) error {
+	srcVal := indirect(reflect.ValueOf(src))
+	if !srcVal.IsValid() || srcVal.Type() != p.srcType {
+		return errors.Errorf("src type must be %s, %T given", p.srcType, src)
+	}
+	dstVal := reflect.ValueOf(dst)
+	if !dstVal.IsValid() || dstVal.Type() != p.dstType {
+		return errors.Errorf("dst type must be %s, %T given", p.dstType, dst)
+	}
+	dstVal = indirect(dstVal)
+	if dstVal.Kind() != reflect.Struct {
+		return errors.Errorf("dst kind must be a struct, %s given", dstVal.Kind())
+	}
+	userOptions := *p.options
+	userOptions.plan = p
+	if userOptions.StrictPaths {
+		userOptions.pathTracker = newPathTracker()
+	}
+	if err := structToStruct(p.filter, &srcVal, &dstVal, &userOptions); err != nil {
+		return err
+	}
+	return checkStrictPaths(p.filter, &userOptions)
+}
+
+// kindsCompatible reports whether the values of the given types can be copied without the converter hooks, see
+// ensureCompatible.
+func kindsCompatible(srcType, dstType reflect.Type) bool {
+	srcKind := srcType.Kind()
+	if srcKind == reflect.Ptr {
+		srcKind = srcType.Elem().Kind()
+	}
+	dstKind := dstType.Kind()
+	if dstKind == reflect.Ptr {
+		dstKind = dstType.Elem().Kind()
+	}
+	return srcKind == dstKind
+}
+
+// mentionsAnyField returns true if the given filter explicitly has an entry for any of the given field names.
+func mentionsAnyField(filter FieldFilter, fieldNames []string) bool {
+	for _, name := range fieldNames {
+		if mentionsField(filter, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package fieldmask_utils_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+func TestPlan_CopyMatchesStructToStruct(t *testing.T) {
+	testCases := []struct {
+		name string
+		mask fieldmask_utils.FieldFilter
+		src  *testproto.User
+		dst  *testproto.User
+	}{
+		{
+			name: "nested fields",
+			mask: fieldmask_utils.MaskFromString(
+				"Id,Avatar{OriginalUrl},Tags,Images,Permissions,Friends{Images{ResizedUrl}},Name{MaleName},ExtraUser{Id,Avatar{OriginalUrl}}"),
+			src: testUserFull,
+			dst: &testproto.User{},
+		},
+		{
+			name: "empty mask",
+			mask: fieldmask_utils.Mask{},
+			src:  testUserFull,
+			dst:  &testproto.User{Username: "dst"},
+		},
+		{
+			name: "inverse mask",
+			mask: fieldmask_utils.MaskInverseFromString("Id,Friends{Username,Avatar{ResizedUrl}}"),
+			src:  testUserFull,
+			dst:  &testproto.User{Id: 10},
+		},
+		{
+			name: "wildcard",
+			mask: fieldmask_utils.MaskFromString("Images{*{OriginalUrl}},Meta{*}"),
+			src:  testUserFull,
+			dst:  &testproto.User{Images: []*testproto.Image{{ResizedUrl: "dst.jpg"}}},
+		},
+		{
+			name: "oneof member clears dst",
+			mask: fieldmask_utils.MaskFromString("MaleName"),
+			src:  &testproto.User{Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:  &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+		{
+			name: "oneof member inverse mask",
+			mask: fieldmask_utils.MaskInverseFromString("MaleName"),
+			src:  &testproto.User{Id: 1, Name: &testproto.User_FemaleName{FemaleName: "Dana"}},
+			dst:  &testproto.User{Name: &testproto.User_MaleName{MaleName: "John"}},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			plan, err := fieldmask_utils.Compile(testCase.mask, reflect.TypeOf(testCase.src), reflect.TypeOf(testCase.dst))
+			require.NoError(t, err)
+
+			expected := proto.Clone(testCase.dst).(*testproto.User)
+			require.NoError(t, fieldmask_utils.StructToStruct(testCase.mask, testCase.src, expected))
+
+			// The plan is reused for several copies.
+			for i := 0; i < 2; i++ {
+				dst := proto.Clone(testCase.dst).(*testproto.User)
+				require.NoError(t, plan.Copy(testCase.src, dst))
+				assert.True(t, proto.Equal(expected, dst), "expected %v, got %v", expected, dst)
+			}
+		})
+	}
+}
+
+func TestPlan_Copy(t *testing.T) {
+	type Image struct {
+		URL  string
+		Size int
+	}
+	type SrcUser struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Avatar *Image `json:"avatar"`
+		Images []Image
+		Meta   map[string]Image
+	}
+	type DstUser struct {
+		ID     int
+		Name   string
+		Avatar *Image
+		Images []Image
+		Meta   map[string]Image
+	}
+	src := &SrcUser{
+		ID:     1,
+		Name:   "name",
+		Avatar: &Image{URL: "avatar.jpg", Size: 10},
+		Images: []Image{{URL: "1.jpg", Size: 1}, {URL: "2.jpg", Size: 2}},
+		Meta:   map[string]Image{"a": {URL: "a.jpg", Size: 3}, "b": {URL: "b.jpg", Size: 4}},
+	}
+	mask := fieldmask_utils.MaskFromString("id,avatar{URL},Images{Size},Meta{a{URL}}")
+	plan, err := fieldmask_utils.Compile(mask, reflect.TypeOf(SrcUser{}), reflect.TypeOf(&DstUser{}),
+		fieldmask_utils.WithSrcTag("json"))
+	require.NoError(t, err)
+
+	dst := &DstUser{Name: "dst", Meta: map[string]Image{"b": {URL: "dst.jpg"}}}
+	require.NoError(t, plan.Copy(src, dst))
+	assert.Equal(t, &DstUser{
+		ID:     1,
+		Name:   "dst",
+		Avatar: &Image{URL: "avatar.jpg"},
+		Images: []Image{{Size: 1}, {Size: 2}},
+		Meta:   map[string]Image{"a": {URL: "a.jpg"}, "b": {URL: "dst.jpg"}},
+	}, dst)
+
+	// Struct values are accepted as src the same way pointers are.
+	dst = &DstUser{}
+	require.NoError(t, plan.Copy(*src, dst))
+	assert.Equal(t, 1, dst.ID)
+}
+
+func TestPlan_CopyConcurrently(t *testing.T) {
+	mask := fieldmask_utils.MaskFromString("Id,Avatar{OriginalUrl},Friends{Username,Images{ResizedUrl}}")
+	plan, err := fieldmask_utils.Compile(mask, reflect.TypeOf(&testproto.User{}), reflect.TypeOf(&testproto.User{}))
+	require.NoError(t, err)
+
+	expected := &testproto.User{}
+	require.NoError(t, fieldmask_utils.StructToStruct(mask, testUserFull, expected))
+
+	var wg sync.WaitGroup
+	results := make([]*testproto.User, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = &testproto.User{}
+			assert.NoError(t, plan.Copy(testUserFull, results[i]))
+		}(i)
+	}
+	wg.Wait()
+	for _, result := range results {
+		assert.True(t, proto.Equal(expected, result), "expected %v, got %v", expected, result)
+	}
+}
+
+func TestPlan_CopyWithStrictPaths(t *testing.T) {
+	type A struct {
+		Field1 string
+		Field2 int
+	}
+	mask := fieldmask_utils.MaskFromString("Field1,Unknown")
+	plan, err := fieldmask_utils.Compile(mask, reflect.TypeOf(A{}), reflect.TypeOf(&A{}), fieldmask_utils.WithStrictPaths())
+	require.NoError(t, err)
+
+	// Every copy reports the unknown paths on its own.
+	for i := 0; i < 2; i++ {
+		dst := &A{}
+		err = plan.Copy(&A{Field1: "src"}, dst)
+		var unknownPathsErr *fieldmask_utils.UnknownPathsError
+		require.ErrorAs(t, err, &unknownPathsErr)
+		assert.Equal(t, []string{"Unknown"}, unknownPathsErr.Paths)
+		assert.Equal(t, &A{Field1: "src"}, dst)
+	}
+}
+
+func TestCompile_Failure(t *testing.T) {
+	type A struct {
+		Field1 string
+		Field2 *A
+	}
+	type B struct {
+		Field1 int
+		Field2 *B
+	}
+	type C struct {
+		Field1 string
+	}
+	testCases := []struct {
+		name    string
+		mask    fieldmask_utils.FieldFilter
+		srcType reflect.Type
+		dstType reflect.Type
+	}{
+		{"dst is not a pointer", fieldmask_utils.Mask{}, reflect.TypeOf(A{}), reflect.TypeOf(A{})},
+		{"src is not a struct", fieldmask_utils.Mask{}, reflect.TypeOf(""), reflect.TypeOf(&A{})},
+		{"dst is not a pointer to a struct", fieldmask_utils.Mask{}, reflect.TypeOf(A{}), reflect.TypeOf(new(int))},
+		{"nil type", fieldmask_utils.Mask{}, nil, reflect.TypeOf(&A{})},
+		{"missing dst field", fieldmask_utils.MaskFromString("Field2"), reflect.TypeOf(A{}), reflect.TypeOf(&C{})},
+		{"nested kind mismatch", fieldmask_utils.MaskFromString("Field2{Field2{Field1}}"), reflect.TypeOf(A{}),
+			reflect.TypeOf(&B{})},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			plan, err := fieldmask_utils.Compile(testCase.mask, testCase.srcType, testCase.dstType)
+			assert.Error(t, err)
+			assert.Nil(t, plan)
+		})
+	}
+}
+
+func TestCompile_WithConverterHook(t *testing.T) {
+	type A struct {
+		Field1 string
+	}
+	type B struct {
+		Field1 []string
+	}
+	mask := fieldmask_utils.MaskFromString("Field1")
+	_, err := fieldmask_utils.Compile(mask, reflect.TypeOf(A{}), reflect.TypeOf(&B{}))
+	require.Error(t, err)
+
+	plan, err := fieldmask_utils.Compile(mask, reflect.TypeOf(A{}), reflect.TypeOf(&B{}),
+		fieldmask_utils.WithConverterHook(func(src, dst *reflect.Value) (interface{}, error) {
+			return []string{src.String()}, nil
+		}))
+	require.NoError(t, err)
+	dst := &B{}
+	require.NoError(t, plan.Copy(A{Field1: "value"}, dst))
+	assert.Equal(t, &B{Field1: []string{"value"}}, dst)
+}
+
+func TestPlan_CopyFailure(t *testing.T) {
+	type A struct {
+		Field1 string
+	}
+	type B struct {
+		Field1 string
+	}
+	plan, err := fieldmask_utils.Compile(fieldmask_utils.Mask{}, reflect.TypeOf(A{}), reflect.TypeOf(&A{}))
+	require.NoError(t, err)
+
+	assert.Error(t, plan.Copy(&B{}, &A{}))
+	assert.Error(t, plan.Copy(&A{}, &B{}))
+	assert.Error(t, plan.Copy(&A{}, A{}))
+	assert.Error(t, plan.Copy(nil, &A{}))
+	assert.Error(t, plan.Copy(&A{}, nil))
+	assert.Error(t, plan.Copy(&A{}, (*A)(nil)))
+}
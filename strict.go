@@ -0,0 +1,246 @@
+package fieldmask_utils
+
+import (
+	"reflect"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// pathTracker records which entries of the filters are matched by the source struct fields while copying.
+// Filters are identified by their underlying maps, so only map based filters like Mask and MaskInverse are tracked.
+// A sub-filter shared by several entries (the same map set for different fields) is tracked once: its entries are
+// matched if any of the structs it is applied to has the corresponding fields.
+type pathTracker struct {
+	// visited holds the filters which have been applied to a struct.
+	visited map[uintptr]bool
+	// matched holds the names of the entries of the filters which have been matched by a struct field.
+	matched map[uintptr]map[string]bool
+}
+
+func newPathTracker() *pathTracker {
+	return &pathTracker{visited: make(map[uintptr]bool), matched: make(map[uintptr]map[string]bool)}
+}
+
+// visitStruct marks the given filter as applied to a struct of the given type: all the fields of the struct (and the
+// Wildcard) are matched. The members of a oneof are also matched in the filter of the oneof field, as only one of them
+// is set at a time.
+func (t *pathTracker) visitStruct(filter FieldFilter, structType reflect.Type, userOptions *options) {
+	fields := structCopyFields(structType, structType, userOptions)
+	fieldNames := make([]string, 0, len(fields))
+	var oneofMembers map[string][]string
+	for _, f := range fields {
+		if !isExported(structType.Field(f.index)) {
+			continue
+		}
+		fieldNames = append(fieldNames, f.srcName)
+		if f.oneof {
+			fieldNames = append(fieldNames, f.members...)
+			if oneofMembers == nil {
+				oneofMembers = make(map[string][]string)
+			}
+			oneofMembers[f.srcName] = f.members
+		}
+	}
+	t.visit(filter, fieldNames, oneofMembers)
+	container, ok := filter.(FieldFilterContainer)
+	if !ok {
+		return
+	}
+	for _, f := range fields {
+		if f.oneof || !isExported(structType.Field(f.index)) {
+			continue
+		}
+		if sub, ok := container.Get(f.srcName); ok && sub != nil {
+			t.visitValue(sub, structType.Field(f.index).Type)
+		}
+	}
+}
+
+// visitValue marks the given filter as applied to a value of the given type if it has no fields, so that none of the
+// entries of the filter are matched, e.g. "Name{X}" for a string Name. The filters of the items of lists and the
+// values of maps are checked the same way. Structs (and interfaces) are visited when they are copied.
+func (t *pathTracker) visitValue(filter FieldFilter, valueType reflect.Type) {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	switch valueType.Kind() {
+	case reflect.Struct, reflect.Interface:
+		return
+
+	case reflect.Slice, reflect.Array:
+		if itemsFilter, ok := itemFilter(filter); ok {
+			t.visitValue(itemsFilter, valueType.Elem())
+		}
+		return
+
+	case reflect.Map:
+		t.visitMapValues(filter, func(sub FieldFilter) { t.visitValue(sub, valueType.Elem()) })
+		return
+	}
+	t.visitScalar(filter)
+}
+
+// visitMapValues calls the given function for the filters of the entries of the given map filter.
+func (t *pathTracker) visitMapValues(filter FieldFilter, visit func(FieldFilter)) {
+	container, ok := filter.(FieldFilterContainer)
+	if !ok {
+		return
+	}
+	keys, err := filterFieldNames(container)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		if sub, ok := container.Get(key); ok && sub != nil {
+			visit(sub)
+		}
+	}
+}
+
+// visitScalar marks the given filter as applied to a value without fields: none of its entries are matched.
+func (t *pathTracker) visitScalar(filter FieldFilter) {
+	if id, ok := filterID(filter); ok {
+		t.visited[id] = true
+	}
+}
+
+// visitMessage is the same as visitStruct for a protobuf message copied by ProtoToProto.
+func (t *pathTracker) visitMessage(filter FieldFilter, md protoreflect.MessageDescriptor) {
+	fields := md.Fields()
+	fieldNames := make([]string, 0, fields.Len())
+	oneofMembers := make(map[string][]string)
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fieldNames = append(fieldNames, goName(fd))
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			oneofMembers[goName(od)] = append(oneofMembers[goName(od)], goName(fd))
+		}
+	}
+	for name := range oneofMembers {
+		fieldNames = append(fieldNames, name)
+	}
+	t.visit(filter, fieldNames, oneofMembers)
+	container, ok := filter.(FieldFilterContainer)
+	if !ok {
+		return
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		sub, ok := container.Get(goName(fd))
+		if !ok || sub == nil {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				t.visitMapValues(sub, t.visitScalar)
+			}
+		case fd.Message() != nil:
+		case fd.IsList():
+			if itemsFilter, ok := itemFilter(sub); ok {
+				t.visitScalar(itemsFilter)
+			}
+		default:
+			t.visitScalar(sub)
+		}
+	}
+}
+
+// visit marks the given filter as applied to a struct or a message with the given fields and oneofs.
+func (t *pathTracker) visit(filter FieldFilter, fieldNames []string, oneofMembers map[string][]string) {
+	id, ok := filterID(filter)
+	if !ok {
+		return
+	}
+	t.visited[id] = true
+	t.match(id, Wildcard)
+	for _, name := range fieldNames {
+		t.match(id, name)
+	}
+	container := filter.(FieldFilterContainer)
+	for name, members := range oneofMembers {
+		sub, ok := container.Get(name)
+		if !ok {
+			continue
+		}
+		if subID, ok := filterID(sub); ok {
+			for _, memberName := range members {
+				t.match(subID, memberName)
+			}
+		}
+	}
+}
+
+func (t *pathTracker) match(id uintptr, fieldName string) {
+	if t.matched[id] == nil {
+		t.matched[id] = make(map[string]bool)
+	}
+	t.matched[id][fieldName] = true
+}
+
+// unmatchedPaths returns the sorted paths of the entries of the given filter that have not been matched by any struct
+// field although their filter has been applied to a struct.
+func (t *pathTracker) unmatchedPaths(filter FieldFilter) ([]string, error) {
+	var paths []string
+	if err := t.appendUnmatchedPaths(&paths, filter, ""); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (t *pathTracker) appendUnmatchedPaths(paths *[]string, filter FieldFilter, prefix string) error {
+	id, ok := filterID(filter)
+	if !ok {
+		return nil
+	}
+	container := filter.(FieldFilterContainer)
+	fieldNames, err := filterFieldNames(container)
+	if err != nil {
+		return err
+	}
+	for _, fieldName := range fieldNames {
+		path := joinPath(prefix, formatPathSegment(fieldName))
+		if t.visited[id] && !t.matched[id][fieldName] {
+			*paths = append(*paths, path)
+			continue
+		}
+		sub, _ := container.Get(fieldName)
+		if sub != nil {
+			if err := t.appendUnmatchedPaths(paths, sub, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// filterID returns the identity of the given filter. Result is false if the filter is not a non-nil map based
+// FieldFilterContainer.
+func filterID(filter FieldFilter) (uintptr, bool) {
+	if _, ok := filter.(FieldFilterContainer); !ok {
+		return 0, false
+	}
+	v := reflect.ValueOf(filter)
+	if v.Kind() != reflect.Map || v.IsNil() || v.Type().Key().Kind() != reflect.String {
+		return 0, false
+	}
+	return v.Pointer(), true
+}
+
+// checkStrictPaths returns an *UnknownPathsError if the WithStrictPaths option is set and some of the entries of the
+// filter have not been matched.
+func checkStrictPaths(filter FieldFilter, userOptions *options) error {
+	if userOptions.pathTracker == nil {
+		return nil
+	}
+	paths, err := userOptions.pathTracker.unmatchedPaths(filter)
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		return &UnknownPathsError{Paths: paths}
+	}
+	return nil
+}
@@ -0,0 +1,723 @@
+// Code generated by protoc-gen-go-fieldmask. DO NOT EDIT.
+// source: test.proto
+
+package testproto
+
+import (
+	errors "errors"
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// CopyMasked copies the fields of m selected by the mask to dst the same way fieldmask_utils.StructToStruct does.
+func (m *Image) CopyMasked(dst *Image, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if dst == nil {
+		return errors.New("dst must not be nil")
+	}
+	if mask.IsEmpty() {
+		dst.unknownFields = m.unknownFields
+		dst.OriginalUrl = m.OriginalUrl
+		dst.ResizedUrl = m.ResizedUrl
+		return nil
+	}
+	if _, ok := mask.Filter("OriginalUrl"); ok {
+		dst.OriginalUrl = m.OriginalUrl
+	}
+	if _, ok := mask.Filter("ResizedUrl"); ok {
+		dst.ResizedUrl = m.ResizedUrl
+	}
+	return nil
+}
+
+// ToMapMasked copies the fields of m selected by the mask to the dst map the same way fieldmask_utils.StructToMap does.
+func (m *Image) ToMapMasked(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if _, ok := mask.Filter("OriginalUrl"); ok {
+		dst["OriginalUrl"] = m.OriginalUrl
+	}
+	if _, ok := mask.Filter("ResizedUrl"); ok {
+		dst["ResizedUrl"] = m.ResizedUrl
+	}
+	return nil
+}
+
+// CopyMasked copies the fields of m selected by the mask to dst the same way fieldmask_utils.StructToStruct does.
+func (m *Metrics) CopyMasked(dst *Metrics, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if dst == nil {
+		return errors.New("dst must not be nil")
+	}
+	if mask.IsEmpty() {
+		dst.unknownFields = m.unknownFields
+		dst.Height = m.Height
+		dst.Weight = m.Weight
+		return nil
+	}
+	if _, ok := mask.Filter("Height"); ok {
+		dst.Height = m.Height
+	}
+	if _, ok := mask.Filter("Weight"); ok {
+		dst.Weight = m.Weight
+	}
+	return nil
+}
+
+// ToMapMasked copies the fields of m selected by the mask to the dst map the same way fieldmask_utils.StructToMap does.
+func (m *Metrics) ToMapMasked(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if _, ok := mask.Filter("Height"); ok {
+		dst["Height"] = m.Height
+	}
+	if _, ok := mask.Filter("Weight"); ok {
+		dst["Weight"] = m.Weight
+	}
+	return nil
+}
+
+// CopyMasked copies the fields of m selected by the mask to dst the same way fieldmask_utils.StructToStruct does.
+func (m *User) CopyMasked(dst *User, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if dst == nil {
+		return errors.New("dst must not be nil")
+	}
+	if mask.IsEmpty() {
+		dst.unknownFields = m.unknownFields
+		dst.Id = m.Id
+		dst.Username = m.Username
+		dst.Role = m.Role
+		dst.Meta = m.Meta
+		dst.Deactivated = m.Deactivated
+		dst.Permissions = m.Permissions
+		dst.Name = m.Name
+		dst.Details = m.Details
+		dst.Images = m.Images
+		dst.Avatar = m.Avatar
+		dst.Tags = m.Tags
+		dst.Friends = m.Friends
+		dst.ExtraUser = m.ExtraUser
+		dst.ImagesBySize = m.ImagesBySize
+		dst.Alias = m.Alias
+		return nil
+	}
+	if _, ok := mask.Filter("Id"); ok {
+		dst.Id = m.Id
+	}
+	if _, ok := mask.Filter("Username"); ok {
+		dst.Username = m.Username
+	}
+	if _, ok := mask.Filter("Role"); ok {
+		dst.Role = m.Role
+	}
+	if sub, ok := mask.Filter("Meta"); ok {
+		if sub.IsEmpty() && m.Meta == nil {
+			dst.Meta = nil
+		} else {
+			if sub.IsEmpty() {
+				dst.Meta = make(map[string]string, len(m.Meta))
+			}
+			for k := range dst.Meta {
+				if _, ok := fieldmask_utils.MapKeyFilter(sub, k); ok {
+					if _, ok := m.Meta[k]; !ok {
+						delete(dst.Meta, k)
+					}
+				}
+			}
+			for k, v := range m.Meta {
+				_, ok := fieldmask_utils.MapKeyFilter(sub, k)
+				if !ok {
+					continue
+				}
+				if dst.Meta == nil {
+					dst.Meta = make(map[string]string)
+				}
+				dst.Meta[k] = v
+			}
+		}
+	}
+	if _, ok := mask.Filter("Deactivated"); ok {
+		dst.Deactivated = m.Deactivated
+	}
+	if sub, ok := mask.Filter("Permissions"); ok {
+		if m.Permissions == nil {
+			dst.Permissions = nil
+		} else if _, ok := fieldmask_utils.ItemFilter(sub); ok {
+			dst.Permissions = append(dst.Permissions[:0], m.Permissions...)
+		}
+	}
+	if err := m.copyMaskedName(dst, mask); err != nil {
+		return err
+	}
+	if sub, ok := mask.Filter("Details"); ok {
+		if m.Details == nil {
+			dst.Details = nil
+		} else if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			for i, item := range m.Details {
+				if i == len(dst.Details) {
+					dst.Details = append(dst.Details, nil)
+				}
+				if err := fieldmask_utils.CopyAny(items, item, &dst.Details[i]); err != nil {
+					return err
+				}
+			}
+			dst.Details = dst.Details[:len(m.Details)]
+		}
+	}
+	if sub, ok := mask.Filter("Images"); ok {
+		if m.Images == nil {
+			dst.Images = nil
+		} else if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			for i, item := range m.Images {
+				if i == len(dst.Images) {
+					dst.Images = append(dst.Images, nil)
+				}
+				if item == nil {
+					dst.Images[i] = nil
+				} else {
+					if dst.Images[i] == nil {
+						dst.Images[i] = new(Image)
+					}
+					if err := item.CopyMasked(dst.Images[i], items); err != nil {
+						return err
+					}
+				}
+			}
+			dst.Images = dst.Images[:len(m.Images)]
+		}
+	}
+	if sub, ok := mask.Filter("Avatar"); ok {
+		if m.Avatar == nil {
+			dst.Avatar = nil
+		} else {
+			if dst.Avatar == nil {
+				dst.Avatar = new(Image)
+			}
+			if err := m.Avatar.CopyMasked(dst.Avatar, sub); err != nil {
+				return err
+			}
+		}
+	}
+	if sub, ok := mask.Filter("Tags"); ok {
+		if m.Tags == nil {
+			dst.Tags = nil
+		} else if _, ok := fieldmask_utils.ItemFilter(sub); ok {
+			dst.Tags = append(dst.Tags[:0], m.Tags...)
+		}
+	}
+	if sub, ok := mask.Filter("Friends"); ok {
+		if m.Friends == nil {
+			dst.Friends = nil
+		} else if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			for i, item := range m.Friends {
+				if i == len(dst.Friends) {
+					dst.Friends = append(dst.Friends, nil)
+				}
+				if item == nil {
+					dst.Friends[i] = nil
+				} else {
+					if dst.Friends[i] == nil {
+						dst.Friends[i] = new(User)
+					}
+					if err := item.CopyMasked(dst.Friends[i], items); err != nil {
+						return err
+					}
+				}
+			}
+			dst.Friends = dst.Friends[:len(m.Friends)]
+		}
+	}
+	if sub, ok := mask.Filter("ExtraUser"); ok {
+		if err := fieldmask_utils.CopyAny(sub, m.ExtraUser, &dst.ExtraUser); err != nil {
+			return err
+		}
+	}
+	if sub, ok := mask.Filter("ImagesBySize"); ok {
+		if sub.IsEmpty() && m.ImagesBySize == nil {
+			dst.ImagesBySize = nil
+		} else {
+			if sub.IsEmpty() {
+				dst.ImagesBySize = make(map[string]*Image, len(m.ImagesBySize))
+			}
+			for k := range dst.ImagesBySize {
+				if _, ok := fieldmask_utils.MapKeyFilter(sub, k); ok {
+					if _, ok := m.ImagesBySize[k]; !ok {
+						delete(dst.ImagesBySize, k)
+					}
+				}
+			}
+			for k, v := range m.ImagesBySize {
+				valueFilter, ok := fieldmask_utils.MapKeyFilter(sub, k)
+				if !ok {
+					continue
+				}
+				if dst.ImagesBySize == nil {
+					dst.ImagesBySize = make(map[string]*Image)
+				}
+				d := dst.ImagesBySize[k]
+				if v == nil {
+					d = nil
+				} else {
+					if d == nil {
+						d = new(Image)
+					}
+					if err := v.CopyMasked(d, valueFilter); err != nil {
+						return err
+					}
+				}
+				dst.ImagesBySize[k] = d
+			}
+		}
+	}
+	if _, ok := mask.Filter("Alias"); ok {
+		if m.Alias == nil {
+			dst.Alias = nil
+		} else {
+			if dst.Alias == nil {
+				dst.Alias = new(string)
+			}
+			*dst.Alias = *m.Alias
+		}
+	}
+	return nil
+}
+
+// copyMaskedName copies the Name oneof of m to dst, see CopyMasked.
+// The members may be selected by the mask either directly by their names or through the oneof field.
+func (m *User) copyMaskedName(dst *User, mask fieldmask_utils.FieldFilter) error {
+	srcMember, dstMember := "", ""
+	switch m.Name.(type) {
+	case *User_MaleName:
+		srcMember = "MaleName"
+	case *User_FemaleName:
+		srcMember = "FemaleName"
+	}
+	switch dst.Name.(type) {
+	case *User_MaleName:
+		dstMember = "MaleName"
+	case *User_FemaleName:
+		dstMember = "FemaleName"
+	}
+	if (srcMember != "" && fieldmask_utils.MentionsField(mask, srcMember)) || (dstMember != "" && fieldmask_utils.MentionsField(mask, dstMember)) {
+		if srcMember != "" {
+			if _, ok := fieldmask_utils.OneofMemberFilter(mask, "Name", srcMember); ok {
+				switch s := m.Name.(type) {
+				case *User_MaleName:
+					d, ok := dst.Name.(*User_MaleName)
+					if !ok {
+						d = &User_MaleName{}
+						dst.Name = d
+					}
+					d.MaleName = s.MaleName
+				case *User_FemaleName:
+					d, ok := dst.Name.(*User_FemaleName)
+					if !ok {
+						d = &User_FemaleName{}
+						dst.Name = d
+					}
+					d.FemaleName = s.FemaleName
+				}
+				return nil
+			}
+		}
+		if dstMember != "" && dstMember != srcMember {
+			if _, ok := fieldmask_utils.OneofMemberFilter(mask, "Name", dstMember); ok {
+				dst.Name = nil
+			}
+		}
+		return nil
+	}
+
+	sub, ok := mask.Filter("Name")
+	if !ok {
+		return nil
+	}
+	switch s := m.Name.(type) {
+	case nil:
+		dst.Name = nil
+	case *User_MaleName:
+		if dst.Name == nil {
+			dst.Name = &User_MaleName{}
+		}
+		d, ok := dst.Name.(*User_MaleName)
+		if !ok {
+			if _, ok := sub.Filter("MaleName"); ok {
+				return errors.New("Can't set a value on a destination field MaleName")
+			}
+		} else if sub.IsEmpty() {
+			*d = *s
+		} else if _, ok := sub.Filter("MaleName"); ok {
+			d.MaleName = s.MaleName
+		}
+	case *User_FemaleName:
+		if dst.Name == nil {
+			dst.Name = &User_FemaleName{}
+		}
+		d, ok := dst.Name.(*User_FemaleName)
+		if !ok {
+			if _, ok := sub.Filter("FemaleName"); ok {
+				return errors.New("Can't set a value on a destination field FemaleName")
+			}
+		} else if sub.IsEmpty() {
+			*d = *s
+		} else if _, ok := sub.Filter("FemaleName"); ok {
+			d.FemaleName = s.FemaleName
+		}
+	}
+	return nil
+}
+
+// ToMapMasked copies the fields of m selected by the mask to the dst map the same way fieldmask_utils.StructToMap does.
+func (m *User) ToMapMasked(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if _, ok := mask.Filter("Id"); ok {
+		dst["Id"] = m.Id
+	}
+	if _, ok := mask.Filter("Username"); ok {
+		dst["Username"] = m.Username
+	}
+	if _, ok := mask.Filter("Role"); ok {
+		dst["Role"] = m.Role
+	}
+	if sub, ok := mask.Filter("Meta"); ok {
+		if sub.IsEmpty() {
+			dst["Meta"] = m.Meta
+		} else {
+			result := make(map[string]string)
+			if existing, ok := dst["Meta"].(map[string]string); ok {
+				for k, v := range existing {
+					result[k] = v
+				}
+			}
+			for k := range result {
+				if _, ok := fieldmask_utils.MapKeyFilter(sub, k); ok {
+					if _, ok := m.Meta[k]; !ok {
+						delete(result, k)
+					}
+				}
+			}
+			for k, v := range m.Meta {
+				_, ok := fieldmask_utils.MapKeyFilter(sub, k)
+				if !ok {
+					continue
+				}
+				result[k] = v
+			}
+			dst["Meta"] = result
+		}
+	}
+	if _, ok := mask.Filter("Deactivated"); ok {
+		dst["Deactivated"] = m.Deactivated
+	}
+	if sub, ok := mask.Filter("Permissions"); ok {
+		if _, ok := fieldmask_utils.ItemFilter(sub); ok {
+			dst["Permissions"] = m.Permissions
+		} else if dst["Permissions"] == nil {
+			dst["Permissions"] = []Permission{}
+		}
+	}
+	if err := m.toMapMaskedName(dst, mask); err != nil {
+		return err
+	}
+	if sub, ok := mask.Filter("Details"); ok {
+		if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			d, ok := dst["Details"].([]map[string]interface{})
+			if !ok {
+				d = []map[string]interface{}{}
+			}
+			for i, item := range m.Details {
+				if i == len(d) {
+					d = append(d, make(map[string]interface{}))
+				}
+				if err := fieldmask_utils.StructToMap(items, item, d[i]); err != nil {
+					return err
+				}
+			}
+			dst["Details"] = d[:len(m.Details)]
+		} else if dst["Details"] == nil {
+			dst["Details"] = []map[string]interface{}{}
+		}
+	}
+	if sub, ok := mask.Filter("Images"); ok {
+		if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			d, ok := dst["Images"].([]map[string]interface{})
+			if !ok {
+				d = []map[string]interface{}{}
+			}
+			for i, item := range m.Images {
+				if i == len(d) {
+					d = append(d, make(map[string]interface{}))
+				}
+				if err := item.ToMapMasked(d[i], items); err != nil {
+					return err
+				}
+			}
+			dst["Images"] = d[:len(m.Images)]
+		} else if dst["Images"] == nil {
+			dst["Images"] = []map[string]interface{}{}
+		}
+	}
+	if sub, ok := mask.Filter("Avatar"); ok {
+		if m.Avatar == nil {
+			if old, ok := dst["Avatar"]; ok && old != nil {
+				delete(dst, "Avatar")
+			} else {
+				dst["Avatar"] = nil
+			}
+		} else {
+			nested, ok := dst["Avatar"].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+			}
+			if err := m.Avatar.ToMapMasked(nested, sub); err != nil {
+				return err
+			}
+			dst["Avatar"] = nested
+		}
+	}
+	if sub, ok := mask.Filter("Tags"); ok {
+		if _, ok := fieldmask_utils.ItemFilter(sub); ok {
+			dst["Tags"] = m.Tags
+		} else if dst["Tags"] == nil {
+			dst["Tags"] = []string{}
+		}
+	}
+	if sub, ok := mask.Filter("Friends"); ok {
+		if items, ok := fieldmask_utils.ItemFilter(sub); ok {
+			d, ok := dst["Friends"].([]map[string]interface{})
+			if !ok {
+				d = []map[string]interface{}{}
+			}
+			for i, item := range m.Friends {
+				if i == len(d) {
+					d = append(d, make(map[string]interface{}))
+				}
+				if err := item.ToMapMasked(d[i], items); err != nil {
+					return err
+				}
+			}
+			dst["Friends"] = d[:len(m.Friends)]
+		} else if dst["Friends"] == nil {
+			dst["Friends"] = []map[string]interface{}{}
+		}
+	}
+	if sub, ok := mask.Filter("ExtraUser"); ok {
+		if m.ExtraUser == nil {
+			if old, ok := dst["ExtraUser"]; ok && old != nil {
+				delete(dst, "ExtraUser")
+			} else {
+				dst["ExtraUser"] = nil
+			}
+		} else {
+			nested, ok := dst["ExtraUser"].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+			}
+			if err := fieldmask_utils.StructToMap(sub, m.ExtraUser, nested); err != nil {
+				return err
+			}
+			dst["ExtraUser"] = nested
+		}
+	}
+	if sub, ok := mask.Filter("ImagesBySize"); ok {
+		if sub.IsEmpty() {
+			dst["ImagesBySize"] = m.ImagesBySize
+		} else {
+			result := make(map[string]map[string]interface{})
+			if existing, ok := dst["ImagesBySize"].(map[string]map[string]interface{}); ok {
+				for k, v := range existing {
+					result[k] = v
+				}
+			}
+			for k := range result {
+				if _, ok := fieldmask_utils.MapKeyFilter(sub, k); ok {
+					if _, ok := m.ImagesBySize[k]; !ok {
+						delete(result, k)
+					}
+				}
+			}
+			for k, v := range m.ImagesBySize {
+				valueFilter, ok := fieldmask_utils.MapKeyFilter(sub, k)
+				if !ok {
+					continue
+				}
+				if v == nil {
+					result[k] = nil
+					continue
+				}
+				d := result[k]
+				if d == nil {
+					d = make(map[string]interface{})
+				}
+				if err := v.ToMapMasked(d, valueFilter); err != nil {
+					return err
+				}
+				result[k] = d
+			}
+			dst["ImagesBySize"] = result
+		}
+	}
+	if _, ok := mask.Filter("Alias"); ok {
+		if m.Alias == nil {
+			if old, ok := dst["Alias"]; ok && old != nil {
+				delete(dst, "Alias")
+			} else {
+				dst["Alias"] = nil
+			}
+		} else {
+			dst["Alias"] = *m.Alias
+		}
+	}
+	return nil
+}
+
+// toMapMaskedName copies the Name oneof of m to the dst map, see ToMapMasked.
+// The members selected by the mask directly by their names are copied under their own keys.
+func (m *User) toMapMaskedName(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error {
+	if fieldmask_utils.MentionsField(mask, "MaleName") ||
+		fieldmask_utils.MentionsField(mask, "FemaleName") {
+		if _, ok := fieldmask_utils.OneofMemberFilter(mask, "Name", "MaleName"); ok {
+			if s, ok := m.Name.(*User_MaleName); ok {
+				dst["MaleName"] = s.MaleName
+			} else {
+				dst["MaleName"] = nil
+			}
+		}
+		if _, ok := fieldmask_utils.OneofMemberFilter(mask, "Name", "FemaleName"); ok {
+			if s, ok := m.Name.(*User_FemaleName); ok {
+				dst["FemaleName"] = s.FemaleName
+			} else {
+				dst["FemaleName"] = nil
+			}
+		}
+		return nil
+	}
+
+	sub, ok := mask.Filter("Name")
+	if !ok {
+		return nil
+	}
+	switch s := m.Name.(type) {
+	case nil:
+		if old, ok := dst["Name"]; ok && old != nil {
+			delete(dst, "Name")
+		} else {
+			dst["Name"] = nil
+		}
+	case *User_MaleName:
+		d, ok := dst["Name"].(map[string]interface{})
+		if !ok {
+			d = make(map[string]interface{})
+		}
+		if _, ok := sub.Filter("MaleName"); ok {
+			d["MaleName"] = s.MaleName
+		}
+		dst["Name"] = d
+	case *User_FemaleName:
+		d, ok := dst["Name"].(map[string]interface{})
+		if !ok {
+			d = make(map[string]interface{})
+		}
+		if _, ok := sub.Filter("FemaleName"); ok {
+			d["FemaleName"] = s.FemaleName
+		}
+		dst["Name"] = d
+	}
+	return nil
+}
+
+// CopyMasked copies the fields of m selected by the mask to dst the same way fieldmask_utils.StructToStruct does.
+func (m *UpdateUserRequest) CopyMasked(dst *UpdateUserRequest, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if dst == nil {
+		return errors.New("dst must not be nil")
+	}
+	if mask.IsEmpty() {
+		dst.unknownFields = m.unknownFields
+		dst.User = m.User
+		dst.FieldMask = m.FieldMask
+		return nil
+	}
+	if sub, ok := mask.Filter("User"); ok {
+		if m.User == nil {
+			dst.User = nil
+		} else {
+			if dst.User == nil {
+				dst.User = new(User)
+			}
+			if err := m.User.CopyMasked(dst.User, sub); err != nil {
+				return err
+			}
+		}
+	}
+	if sub, ok := mask.Filter("FieldMask"); ok {
+		if m.FieldMask == nil {
+			dst.FieldMask = nil
+		} else {
+			if dst.FieldMask == nil {
+				dst.FieldMask = new(fieldmaskpb.FieldMask)
+			}
+			if err := fieldmask_utils.StructToStruct(sub, m.FieldMask, dst.FieldMask); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ToMapMasked copies the fields of m selected by the mask to the dst map the same way fieldmask_utils.StructToMap does.
+func (m *UpdateUserRequest) ToMapMasked(dst map[string]interface{}, mask fieldmask_utils.FieldFilter) error {
+	if m == nil {
+		return errors.New("src must not be nil")
+	}
+	if sub, ok := mask.Filter("User"); ok {
+		if m.User == nil {
+			if old, ok := dst["User"]; ok && old != nil {
+				delete(dst, "User")
+			} else {
+				dst["User"] = nil
+			}
+		} else {
+			nested, ok := dst["User"].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+			}
+			if err := m.User.ToMapMasked(nested, sub); err != nil {
+				return err
+			}
+			dst["User"] = nested
+		}
+	}
+	if sub, ok := mask.Filter("FieldMask"); ok {
+		if m.FieldMask == nil {
+			if old, ok := dst["FieldMask"]; ok && old != nil {
+				delete(dst, "FieldMask")
+			} else {
+				dst["FieldMask"] = nil
+			}
+		} else {
+			nested, ok := dst["FieldMask"].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+			}
+			if err := fieldmask_utils.StructToMap(sub, m.FieldMask, nested); err != nil {
+				return err
+			}
+			dst["FieldMask"] = nested
+		}
+	}
+	return nil
+}
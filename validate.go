@@ -0,0 +1,165 @@
+package fieldmask_utils
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// UnknownPathsError is returned by ValidateFilter if some of the paths of the filter do not exist in the type.
+type UnknownPathsError struct {
+	// Paths are the sorted unknown paths in the same format Mask.Paths uses, e.g. "Avatar.Url".
+	Paths []string
+}
+
+// Error implements the error interface.
+func (e *UnknownPathsError) Error() string {
+	return fmt.Sprintf("unknown paths: %s", strings.Join(e.Paths, ", "))
+}
+
+// ValidateFilter checks that every field name mentioned in the given filter exists in the given type, so the filter
+// does not silently ignore mistyped field names when used with StructToStruct or StructToMap.
+// The type is walked through pointers, slices, arrays and map values. The field names are resolved the same way the
+// copying functions do (see WithSrcTag), members of protobuf oneofs may be addressed by their own names.
+// The fields of google.protobuf.Any messages, interfaces and the Wildcard entries are not validated as their types are
+// only known at runtime.
+// An *UnknownPathsError listing all the unknown paths is returned if the filter is invalid.
+func ValidateFilter(filter FieldFilter, typ reflect.Type, opts ...Option) error {
+	userOptions := newDefaultOptions()
+	for _, o := range opts {
+		o(userOptions)
+	}
+	container, ok := filter.(FieldFilterContainer)
+	if !ok || (container != nil && reflect.TypeOf(container).Kind() != reflect.Map) {
+		return errors.Errorf("unsupported FieldFilter %T", filter)
+	}
+	var unknownPaths []string
+	if err := validateFilter(container, typ, "", userOptions, &unknownPaths); err != nil {
+		return err
+	}
+	if len(unknownPaths) > 0 {
+		sort.Strings(unknownPaths)
+		return &UnknownPathsError{Paths: unknownPaths}
+	}
+	return nil
+}
+
+// validateFilter appends the paths of the filter which do not exist in the given type to unknownPaths.
+func validateFilter(filter FieldFilterContainer, typ reflect.Type, path string, userOptions *options, unknownPaths *[]string) error {
+	if isEmptyFilter(filter) || typ == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		if items, ok := filter.Get(Wildcard); ok {
+			return validateFilter(items, typ.Elem(), joinPath(path, Wildcard), userOptions, unknownPaths)
+		}
+		return validateFilter(filter, typ.Elem(), path, userOptions, unknownPaths)
+
+	case reflect.Map:
+		keys, err := filterFieldNames(filter)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			sub, _ := filter.Get(key)
+			keyPath := joinPath(path, formatPathSegment(key))
+			if err := validateFilter(sub, typ.Elem(), keyPath, userOptions, unknownPaths); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Interface:
+		// The type of the value is only known at runtime.
+
+	case reflect.Struct:
+		if typ == reflect.TypeOf(anypb.Any{}) {
+			// The type of the message is only known at runtime.
+			return nil
+		}
+		names, err := filterFieldNames(filter)
+		if err != nil {
+			return err
+		}
+		fields, oneofs := structFieldTypes(typ, userOptions)
+		for _, name := range names {
+			if name == Wildcard {
+				continue
+			}
+			sub, _ := filter.Get(name)
+			subPath := joinPath(path, formatPathSegment(name))
+			if members, ok := oneofs[name]; ok && !isEmptyFilter(sub) {
+				// The oneof members addressed through the oneof field.
+				memberNames, err := filterFieldNames(sub)
+				if err != nil {
+					return err
+				}
+				for _, memberName := range memberNames {
+					memberSub, _ := sub.Get(memberName)
+					memberPath := joinPath(subPath, formatPathSegment(memberName))
+					memberType, ok := members[memberName]
+					if !ok {
+						*unknownPaths = append(*unknownPaths, memberPath)
+						continue
+					}
+					if err := validateFilter(memberSub, memberType, memberPath, userOptions, unknownPaths); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			fieldType, ok := fields[name]
+			if !ok {
+				*unknownPaths = append(*unknownPaths, subPath)
+				continue
+			}
+			if err := validateFilter(sub, fieldType, subPath, userOptions, unknownPaths); err != nil {
+				return err
+			}
+		}
+
+	default:
+		// Scalar values have no fields.
+		names, err := filterFieldNames(filter)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			*unknownPaths = append(*unknownPaths, joinPath(path, formatPathSegment(name)))
+		}
+	}
+	return nil
+}
+
+// structFieldTypes returns the types of the exported fields of the given struct type by their names in a FieldFilter.
+// The members of protobuf oneofs are included in the fields and are also grouped by the name of their oneof field.
+func structFieldTypes(structType reflect.Type, userOptions *options) (map[string]reflect.Type, map[string]map[string]reflect.Type) {
+	fields := make(map[string]reflect.Type)
+	oneofs := make(map[string]map[string]reflect.Type)
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := fieldName(userOptions.SrcTag, f)
+		fields[name] = f.Type
+		if !isOneof(f) {
+			continue
+		}
+		members := make(map[string]reflect.Type)
+		for _, member := range oneofMemberFields(structType, f) {
+			memberName := fieldName(userOptions.SrcTag, member)
+			members[memberName] = member.Type
+			fields[memberName] = member.Type
+		}
+		oneofs[name] = members
+	}
+	return fields, oneofs
+}
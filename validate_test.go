@@ -0,0 +1,97 @@
+package fieldmask_utils_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	fieldmask_utils "github.com/mennanov/fieldmask-utils"
+	"github.com/mennanov/fieldmask-utils/testproto"
+)
+
+type validateB struct {
+	X int `json:"x"`
+	Y int `json:"y,omitempty"`
+}
+
+type validateA struct {
+	Ptr       *validateB             `json:"ptr"`
+	Slice     []*validateB           `json:"slice"`
+	Array     [2]validateB           `json:"array"`
+	Map       map[string]validateB   `json:"map"`
+	Interface interface{}            `json:"interface"`
+	Scalar    string                 `json:"scalar"`
+	Nested    map[int][]*[]validateB `json:"nested"`
+	private   int
+}
+
+func TestValidateFilter_Success(t *testing.T) {
+	testCases := []string{
+		"",
+		"Ptr,Slice,Array,Map,Interface,Scalar,Nested",
+		"Ptr{X},Slice{Y},Array{X,Y},Map{a{X},b},Interface{Anything{Goes}},Nested{1{X}}",
+		"Slice{*{X}},Map{*{Y}},*",
+	}
+	for _, testCase := range testCases {
+		assert.NoError(t, fieldmask_utils.ValidateFilter(fieldmask_utils.MaskFromString(testCase), reflect.TypeOf(validateA{})), testCase)
+		assert.NoError(t, fieldmask_utils.ValidateFilter(fieldmask_utils.MaskInverseFromString(testCase), reflect.TypeOf(&validateA{})), testCase)
+	}
+}
+
+func TestValidateFilter_UnknownPaths(t *testing.T) {
+	testCases := []struct {
+		mask          string
+		expectedPaths []string
+	}{
+		{"Ptr{Z},Unknown", []string{"Ptr.Z", "Unknown"}},
+		{"Slice{X,Z},Array{Q}", []string{"Array.Q", "Slice.Z"}},
+		{"Slice{*{Z}}", []string{"Slice.*.Z"}},
+		{"Map{a{X,Z},`b.c`{W}}", []string{"Map.`b.c`.W", "Map.a.Z"}},
+		{"Scalar{foo},Nested{1{X{Y}}}", []string{"Nested.1.X.Y", "Scalar.foo"}},
+		{"private,ptr", []string{"private", "ptr"}},
+	}
+	for _, testCase := range testCases {
+		mask, err := fieldmask_utils.ParseMask(testCase.mask, func(s string) string { return s })
+		require.NoError(t, err, testCase.mask)
+		err = fieldmask_utils.ValidateFilter(mask, reflect.TypeOf(&validateA{}))
+		var unknownPathsErr *fieldmask_utils.UnknownPathsError
+		require.True(t, errors.As(err, &unknownPathsErr), testCase.mask)
+		assert.Equal(t, testCase.expectedPaths, unknownPathsErr.Paths, testCase.mask)
+	}
+}
+
+func TestValidateFilter_Error(t *testing.T) {
+	err := fieldmask_utils.ValidateFilter(fieldmask_utils.MaskFromString("Ptr{Z},Unknown"), reflect.TypeOf(validateA{}))
+	assert.EqualError(t, err, "unknown paths: Ptr.Z, Unknown")
+}
+
+func TestValidateFilter_WithSrcTag(t *testing.T) {
+	mask := fieldmask_utils.MaskFromString("ptr{x},slice{y},Scalar")
+	err := fieldmask_utils.ValidateFilter(mask, reflect.TypeOf(validateA{}), fieldmask_utils.WithSrcTag("json"))
+	assert.EqualError(t, err, "unknown paths: Scalar")
+}
+
+func TestValidateFilter_Proto(t *testing.T) {
+	userType := reflect.TypeOf(&testproto.User{})
+	mask := fieldmask_utils.MaskFromString(
+		"Id,MaleName,Name{FemaleName},Avatar{OriginalUrl},Friends{Username,Images{ResizedUrl}},ExtraUser{Anything}," +
+			"Details{Anything},ImagesBySize{small{OriginalUrl}},Meta{color}")
+	assert.NoError(t, fieldmask_utils.ValidateFilter(mask, userType))
+
+	mask = fieldmask_utils.MaskFromString("Usrname,Name{Nickname},Avatar{Url},Friends{MaleName{First}},Meta{color{Red}}")
+	err := fieldmask_utils.ValidateFilter(mask, userType)
+	assert.EqualError(t, err, "unknown paths: Avatar.Url, Friends.MaleName.First, Meta.color.Red, Name.Nickname, Usrname")
+}
+
+type notContainer struct{}
+
+func (notContainer) Filter(string) (fieldmask_utils.FieldFilter, bool) { return nil, false }
+func (notContainer) IsEmpty() bool                                     { return false }
+
+func TestValidateFilter_UnsupportedFilter(t *testing.T) {
+	err := fieldmask_utils.ValidateFilter(notContainer{}, reflect.TypeOf(validateA{}))
+	assert.EqualError(t, err, "unsupported FieldFilter fieldmask_utils_test.notContainer")
+}